@@ -0,0 +1,18 @@
+package numa
+
+import "os"
+
+// Prefault touches every page of b, so the pages are allocated at once
+// according to the memory policy of b, rather than on the first access.
+func Prefault(b []byte) {
+	pagesize := os.Getpagesize()
+	for i := 0; i < len(b); i += pagesize {
+		b[i] = 0
+	}
+}
+
+// roundpage rounds size up to a multiple of the system page size.
+func roundpage(size int) int {
+	pagesize := os.Getpagesize()
+	return (size + pagesize - 1) &^ (pagesize - 1)
+}
@@ -0,0 +1,81 @@
+//go:build linux
+// +build linux
+
+package numa
+
+import (
+	"fmt"
+	"syscall"
+	"unsafe"
+)
+
+// AllocOnNode allocates size bytes memory on the given node, which is out of
+// the Go heap. The size is rounded up to a multiple of the system page size,
+// but the returned slice has the length of size. The memory is bound on the
+// given node strictly by MPOL_BIND, which is the default bind policy of
+// libnuma, so the allocation does not fall back to other nodes, the kernel
+// reclaims or even OOM kills when the node is full. Use AllocOnNodePreferred
+// to fall back. The pages are placed when they are first touched, call
+// Prefault to place them at once. The memory must be released by Free.
+// @numa_alloc_onnode
+func AllocOnNode(size, node int) ([]byte, error) {
+	return allocnode(size, node, MPOL_BIND)
+}
+
+// AllocOnNodePreferred likes AllocOnNode, but the memory is preferred to
+// allocate on the given node by MPOL_PREFERRED, it falls back to other nodes
+// when the node is full, like numa_alloc_onnode after
+// numa_set_bind_policy(0) of libnuma.
+func AllocOnNodePreferred(size, node int) ([]byte, error) {
+	return allocnode(size, node, MPOL_PREFERRED)
+}
+
+func allocnode(size, node, mode int) ([]byte, error) {
+	if node < 0 || node > MaxPossibleNodeID() {
+		return nil, fmt.Errorf("invalided node %d", node)
+	}
+	nodemask := NewBitmask(NodePossibleCount())
+	nodemask.Set(node, true)
+	return alloc(size, mode, nodemask)
+}
+
+// AllocInterleaved allocates size bytes memory interleaved on the given nodes
+// with page granularity, which is out of the Go heap. The memory must be
+// released by Free.
+// @numa_alloc_interleaved_subset
+func AllocInterleaved(size int, nodes Bitmask) ([]byte, error) {
+	return alloc(size, MPOL_INTERLEAVE, nodes)
+}
+
+// AllocLocal allocates size bytes memory on the node of the cpu which first
+// touches the pages, which is out of the Go heap. The memory must be released
+// by Free.
+// @numa_alloc_local
+func AllocLocal(size int) ([]byte, error) {
+	return alloc(size, MPOL_LOCAL, nil)
+}
+
+// Free releases the memory allocated by AllocOnNode, AllocOnNodePreferred,
+// AllocInterleaved or AllocLocal. The b must be the slice returned by them, or a slice of it
+// which has the same start.
+// @numa_free
+func Free(b []byte) error {
+	return syscall.Munmap(b[:cap(b)])
+}
+
+func alloc(size, mode int, nodemask Bitmask) ([]byte, error) {
+	if size <= 0 {
+		return nil, syscall.EINVAL
+	}
+	length := roundpage(size)
+	b, err := syscall.Mmap(-1, 0, length, syscall.PROT_READ|syscall.PROT_WRITE,
+		syscall.MAP_PRIVATE|syscall.MAP_ANONYMOUS)
+	if err != nil {
+		return nil, err
+	}
+	if err = MBind(unsafe.Pointer(&b[0]), length, mode, 0, nodemask); err != nil {
+		syscall.Munmap(b)
+		return nil, err
+	}
+	return b[:size], nil
+}
@@ -0,0 +1,72 @@
+package numa
+
+import (
+	"os"
+	"testing"
+	"unsafe"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestAlloc(t *testing.T) {
+	if !Available() {
+		t.Skip("skip by not available")
+	}
+	var (
+		assert   = require.New(t)
+		pagesize = os.Getpagesize()
+		size     = 3*pagesize + 1
+		nodemask = memorynodes()
+	)
+	nodeof := func(b []byte) int {
+		node, err := GetMemPolicy(nil, unsafe.Pointer(&b[0]), MPOL_F_NODE|MPOL_F_ADDR)
+		assert.NoError(err)
+		return node
+	}
+
+	nodemask.ForEach(func(node int) bool {
+		for mode, alloc := range map[int]func(int, int) ([]byte, error){
+			MPOL_BIND:      AllocOnNode,
+			MPOL_PREFERRED: AllocOnNodePreferred,
+		} {
+			b, err := alloc(size, node)
+			assert.NoError(err)
+			assert.Len(b, size)
+			assert.Equal(4*pagesize, cap(b))
+			Prefault(b)
+			assert.Equal(node, nodeof(b))
+			m, err := GetMemPolicy(nil, unsafe.Pointer(&b[0]), MPOL_F_ADDR)
+			assert.NoError(err)
+			assert.Equal(mode, m)
+			assert.NoError(Free(b))
+		}
+		return true
+	})
+
+	b, err := AllocInterleaved(size, nodemask)
+	assert.NoError(err)
+	Prefault(b)
+	mode, err := GetMemPolicy(nil, unsafe.Pointer(&b[0]), MPOL_F_ADDR)
+	assert.NoError(err)
+	assert.Equal(MPOL_INTERLEAVE, mode)
+	assert.NoError(Free(b))
+
+	b, err = AllocLocal(size)
+	assert.NoError(err)
+	Prefault(b)
+	assert.True(nodemask.Get(nodeof(b)))
+	assert.Error(Free(b[1:]))
+	assert.NoError(Free(b[:1]))
+	assert.Error(Free(b))
+
+	_, err = AllocOnNode(size, -1)
+	assert.Error(err)
+	_, err = AllocOnNode(size, MaxPossibleNodeID()+1)
+	assert.Error(err)
+	_, err = AllocOnNodePreferred(size, -1)
+	assert.Error(err)
+	_, err = AllocLocal(0)
+	assert.Error(err)
+	_, err = AllocInterleaved(size, NewBitmask(NodePossibleCount()))
+	assert.Error(err)
+}
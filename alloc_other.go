@@ -0,0 +1,33 @@
+//go:build !linux
+// +build !linux
+
+package numa
+
+import "syscall"
+
+// AllocOnNode allocates size bytes memory on the given node.
+func AllocOnNode(size, node int) ([]byte, error) {
+	return nil, syscall.ENOSYS
+}
+
+// AllocOnNodePreferred allocates size bytes memory on the given node
+// preferably.
+func AllocOnNodePreferred(size, node int) ([]byte, error) {
+	return nil, syscall.ENOSYS
+}
+
+// AllocInterleaved allocates size bytes memory interleaved on the given nodes.
+func AllocInterleaved(size int, nodes Bitmask) ([]byte, error) {
+	return nil, syscall.ENOSYS
+}
+
+// AllocLocal allocates size bytes memory on the local node.
+func AllocLocal(size int) ([]byte, error) {
+	return nil, syscall.ENOSYS
+}
+
+// Free releases the memory allocated by AllocOnNode, AllocOnNodePreferred,
+// AllocInterleaved or AllocLocal.
+func Free(b []byte) error {
+	return syscall.ENOSYS
+}
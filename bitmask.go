@@ -1,6 +1,8 @@
 package numa
 
 import (
+	"encoding/json"
+	"flag"
 	"fmt"
 	"math/bits"
 	"strconv"
@@ -74,6 +76,288 @@ func (b Bitmask) Text() string {
 	return strings.Join(s, ",")
 }
 
+// NextSet returns the index of the first set bit which is not less than
+// from, it returns -1 if there is no such bit.
+func (b Bitmask) NextSet(from int) int {
+	if from < 0 {
+		from = 0
+	}
+	n := from / 64
+	if n >= len(b) {
+		return -1
+	}
+	if v := b[n] >> uint64(from%64); v != 0 {
+		return from + bits.TrailingZeros64(v)
+	}
+	for n++; n < len(b); n++ {
+		if b[n] != 0 {
+			return n*64 + bits.TrailingZeros64(b[n])
+		}
+	}
+	return -1
+}
+
+// First returns the index of the lowest set bit, it returns -1 if no bit is
+// set.
+func (b Bitmask) First() int {
+	return b.NextSet(0)
+}
+
+// Last returns the index of the highest set bit, it returns -1 if no bit is
+// set.
+func (b Bitmask) Last() int {
+	for n := len(b) - 1; n >= 0; n-- {
+		if b[n] != 0 {
+			return n*64 + 63 - bits.LeadingZeros64(b[n])
+		}
+	}
+	return -1
+}
+
+// ForEach calls fn with the index of each set bit in ascending order, until
+// fn returns false.
+func (b Bitmask) ForEach(fn func(i int) bool) {
+	for i := b.First(); i >= 0; i = b.NextSet(i + 1) {
+		if !fn(i) {
+			return
+		}
+	}
+}
+
+// HexMask returns the kernel hex mask format of this bitmask, which is the
+// comma-separated 32-bit hex words, most significant first, such as
+// "00000000,0000ff0f". It is the format of /sys/devices/system/node/nodeN/cpumap,
+// /proc/irq/N/smp_affinity and Cpus_allowed, which accepted by taskset.
+// The width is b.Len(), use HexMaskN to get the exact width of kernel.
+func (b Bitmask) HexMask() string {
+	return b.HexMaskN(b.Len())
+}
+
+// HexMaskN returns the kernel hex mask format of the first nbits bits of this
+// bitmask. Like the kernel, the most significant word only has the digits of
+// the remaining bits, e.g. "000f,ff000fff" for 48 bits. So the output is
+// exactly same with the kernel when nbits is the possible cpu count.
+func (b Bitmask) HexMaskN(nbits int) string {
+	if nbits <= 0 {
+		return ""
+	}
+	words := (nbits + 31) / 32
+	s := make([]string, 0, words)
+	for i := words - 1; i >= 0; i-- {
+		width := 32
+		if i == words-1 && nbits%32 != 0 {
+			width = nbits % 32
+		}
+		v := b.word(i/2) >> uint64(i%2*32) & (1<<uint64(width) - 1)
+		s = append(s, fmt.Sprintf("%0*x", (width+3)/4, v))
+	}
+	return strings.Join(s, ",")
+}
+
+// ParseHexMask parses the kernel hex mask format, such as "ff,ffffffff". The
+// length of returned bitmask is enough to hold all the words.
+func ParseHexMask(s string) (Bitmask, error) {
+	s = strings.TrimSpace(s)
+	if s == "" {
+		return nil, fmt.Errorf("invalid hex mask %q", s)
+	}
+	tokens := strings.Split(s, ",")
+	b := NewBitmask(len(tokens) * 32)
+	for i, token := range tokens {
+		if len(token) == 0 || len(token) > 8 {
+			return nil, fmt.Errorf("invalid hex mask %q", s)
+		}
+		v, err := strconv.ParseUint(token, 16, 32)
+		if err != nil {
+			return nil, fmt.Errorf("invalid hex mask %q", s)
+		}
+		j := len(tokens) - 1 - i
+		b[j/2] |= v << uint64(j%2*32)
+	}
+	return b, nil
+}
+
+// ListString returns the kernel cpulist/nodelist format of this bitmask, which
+// compresses the consecutive bits into ranges, such as "0-3,8-11,64".
+func (b Bitmask) ListString() string {
+	var s []string
+	for i := b.First(); i >= 0; {
+		j := i
+		for b.Get(j + 1) {
+			j++
+		}
+		if i == j {
+			s = append(s, strconv.Itoa(i))
+		} else {
+			s = append(s, strconv.Itoa(i)+"-"+strconv.Itoa(j))
+		}
+		i = b.NextSet(j + 1)
+	}
+	return strings.Join(s, ",")
+}
+
+// maxlistbits is the max bit count accepted by ParseList, which is larger
+// than the max NR_CPUS and MAX_NUMNODES of kernel.
+const maxlistbits = 1 << 16
+
+// ParseList parses the kernel cpulist/nodelist format, such as "0-3,8-11,64",
+// which used in cpuset.cpus, Cpus_allowed_list and so on. The stride syntax
+// of kernel is supported, "0-31:2/4" means using the first 2 bits of every 4
+// bits in range 0-31. The length of returned bitmask is enough to hold the
+// max bit. The bits beyond 65535 are rejected, use ParseListN to limit the
+// bits to the width of a kernel mask.
+func ParseList(s string) (Bitmask, error) {
+	return ParseListN(s, maxlistbits)
+}
+
+// ParseListN likes ParseList, but rejects the bits not less than nbits, as
+// the kernel rejects the bits beyond its mask width. E.g. the nbits is
+// CPUPossibleCount() for a cpulist and NodePossibleCount() for a nodelist.
+func ParseListN(s string, nbits int) (Bitmask, error) {
+	var (
+		regions []listregion
+		max     = -1
+	)
+	s = strings.TrimSpace(s)
+	if s == "" {
+		return NewBitmask(0), nil
+	}
+	for _, token := range strings.Split(s, ",") {
+		r, err := parseregion(token)
+		if err != nil {
+			return nil, fmt.Errorf("invalid list %q: %v", s, err)
+		}
+		if r.end >= nbits {
+			return nil, fmt.Errorf("invalid list %q: bit %d is out of range %d", s, r.end, nbits)
+		}
+		if r.end > max {
+			max = r.end
+		}
+		regions = append(regions, r)
+	}
+	b := NewBitmask(max + 1)
+	for _, r := range regions {
+		for i := r.start; i <= r.end; i += r.group {
+			for j := 0; j < r.used && i+j <= r.end; j++ {
+				b.Set(i+j, true)
+			}
+		}
+	}
+	return b, nil
+}
+
+// listregion is a region of the cpulist/nodelist format, which sets the
+// first used bits of every group bits in range [start, end].
+type listregion struct{ start, end, used, group int }
+
+// parseregion parses the region such as "3", "0-7" or "0-31:2/4".
+func parseregion(s string) (r listregion, err error) {
+	atoi := func(s string) (int, error) {
+		n, err := strconv.ParseUint(s, 10, 31)
+		if err != nil {
+			return 0, fmt.Errorf("invalid number %q", s)
+		}
+		return int(n), nil
+	}
+	rng, stride := s, ""
+	i := strings.IndexByte(s, ':')
+	if i >= 0 {
+		rng, stride = s[:i], s[i+1:]
+	}
+	if j := strings.IndexByte(rng, '-'); j >= 0 {
+		if r.start, err = atoi(rng[:j]); err != nil {
+			return
+		}
+		if r.end, err = atoi(rng[j+1:]); err != nil {
+			return
+		}
+	} else {
+		if r.start, err = atoi(rng); err != nil {
+			return
+		}
+		r.end = r.start
+	}
+	if r.start > r.end {
+		err = fmt.Errorf("invalid range %q", s)
+		return
+	}
+	r.used, r.group = 1, 1
+	if i >= 0 {
+		j := strings.IndexByte(stride, '/')
+		if j < 0 {
+			err = fmt.Errorf("invalid stride %q", s)
+			return
+		}
+		if r.used, err = atoi(stride[:j]); err != nil {
+			return
+		}
+		if r.group, err = atoi(stride[j+1:]); err != nil {
+			return
+		}
+		if r.group == 0 || r.used > r.group {
+			err = fmt.Errorf("invalid stride %q", s)
+			return
+		}
+	}
+	return
+}
+
+// MarshalText implements encoding.TextMarshaler, which encodes the bitmask in
+// the list format, such as "0-3,7".
+func (b Bitmask) MarshalText() ([]byte, error) {
+	return []byte(b.ListString()), nil
+}
+
+// UnmarshalText implements encoding.TextUnmarshaler, which decodes the
+// bitmask from the list format. The bits beyond 65535 are rejected like
+// ParseList, so an untrusted input can not allocate a huge bitmask.
+func (b *Bitmask) UnmarshalText(text []byte) error {
+	mask, err := ParseListN(string(text), maxlistbits)
+	if err != nil {
+		return err
+	}
+	*b = mask
+	return nil
+}
+
+// MarshalJSON implements json.Marshaler, which encodes the bitmask as a JSON
+// string in the list format.
+func (b Bitmask) MarshalJSON() ([]byte, error) {
+	return json.Marshal(b.ListString())
+}
+
+// UnmarshalJSON implements json.Unmarshaler, which decodes the bitmask from a
+// JSON string in the list format.
+func (b *Bitmask) UnmarshalJSON(data []byte) error {
+	var s string
+	if err := json.Unmarshal(data, &s); err != nil {
+		return err
+	}
+	return b.UnmarshalText([]byte(s))
+}
+
+// ListValue returns a flag.Value which sets the bitmask p by the list format,
+// so a command line tool can take "--cpus=0-7" directly:
+//
+//	var cpus numa.Bitmask
+//	flag.Var(numa.ListValue(&cpus), "cpus", "the cpus to run on")
+func ListValue(p *Bitmask) flag.Value {
+	return listValue{p}
+}
+
+type listValue struct{ p *Bitmask }
+
+func (v listValue) String() string {
+	if v.p == nil {
+		return ""
+	}
+	return v.p.ListString()
+}
+
+func (v listValue) Set(s string) error { return v.p.UnmarshalText([]byte(s)) }
+
+func (v listValue) Get() interface{} { return *v.p }
+
 // Len returns the bitmask length.
 func (b Bitmask) Len() int { return len(b) * 64 }
 
@@ -84,6 +368,90 @@ func (b Bitmask) Clone() Bitmask {
 	return bb
 }
 
+// And returns a new bitmask which is the intersection of b and o. The
+// bitmasks may have different length, the shorter one is treated as zero
+// extended, and so do the following set operations.
+func (b Bitmask) And(o Bitmask) Bitmask {
+	return b.combine(o, func(x, y uint64) uint64 { return x & y })
+}
+
+// Or returns a new bitmask which is the union of b and o.
+func (b Bitmask) Or(o Bitmask) Bitmask {
+	return b.combine(o, func(x, y uint64) uint64 { return x | y })
+}
+
+// Xor returns a new bitmask which is the symmetric difference of b and o.
+func (b Bitmask) Xor(o Bitmask) Bitmask {
+	return b.combine(o, func(x, y uint64) uint64 { return x ^ y })
+}
+
+// AndNot returns a new bitmask which contains the bits of b but not of o.
+func (b Bitmask) AndNot(o Bitmask) Bitmask {
+	return b.combine(o, func(x, y uint64) uint64 { return x &^ y })
+}
+
+// combine returns a new bitmask which length is the longer one of b and o,
+// and each word is fn(b[i], o[i]).
+func (b Bitmask) combine(o Bitmask, fn func(x, y uint64) uint64) Bitmask {
+	n := len(b)
+	if len(o) > n {
+		n = len(o)
+	}
+	bb := make(Bitmask, n)
+	for i := range bb {
+		bb[i] = fn(b.word(i), o.word(i))
+	}
+	return bb
+}
+
+// word returns the No.i word of this bitmask, zero if it is out of range.
+func (b Bitmask) word(i int) uint64 {
+	if i < len(b) {
+		return b[i]
+	}
+	return 0
+}
+
+// Equal reports whether b and o have the same bits set.
+func (b Bitmask) Equal(o Bitmask) bool {
+	for i := 0; i < len(b) || i < len(o); i++ {
+		if b.word(i) != o.word(i) {
+			return false
+		}
+	}
+	return true
+}
+
+// IsSubsetOf reports whether all bits set in b are also set in o.
+func (b Bitmask) IsSubsetOf(o Bitmask) bool {
+	for i := range b {
+		if b[i]&^o.word(i) != 0 {
+			return false
+		}
+	}
+	return true
+}
+
+// Intersects reports whether b and o have any bit set in common.
+func (b Bitmask) Intersects(o Bitmask) bool {
+	for i := 0; i < len(b) && i < len(o); i++ {
+		if b[i]&o[i] != 0 {
+			return true
+		}
+	}
+	return false
+}
+
+// IsEmpty reports whether no bit is set in this bitmask.
+func (b Bitmask) IsEmpty() bool {
+	for _, v := range b {
+		if v != 0 {
+			return false
+		}
+	}
+	return true
+}
+
 // NewBitmask returns a bitmask, which length always rounded to a multiple of
 // sizeof(uint64). The input param n represents the bit count of this bitmask.
 func NewBitmask(n int) Bitmask {
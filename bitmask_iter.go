@@ -0,0 +1,12 @@
+//go:build go1.23
+// +build go1.23
+
+package numa
+
+import "iter"
+
+// All returns an iterator over the indexes of the set bits in ascending
+// order.
+func (b Bitmask) All() iter.Seq[int] {
+	return b.ForEach
+}
@@ -0,0 +1,38 @@
+//go:build go1.23
+// +build go1.23
+
+package numa
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestBitmaskAll(t *testing.T) {
+	var (
+		assert = require.New(t)
+		mask   = NewBitmask(512)
+		got    []int
+	)
+	for _, i := range []int{0, 64, 65, 511} {
+		mask.Set(i, true)
+	}
+	for i := range mask.All() {
+		got = append(got, i)
+	}
+	assert.Equal([]int{0, 64, 65, 511}, got)
+
+	got = got[:0]
+	for i := range mask.All() {
+		if i > 64 {
+			break
+		}
+		got = append(got, i)
+	}
+	assert.Equal([]int{0, 64}, got)
+
+	for range Bitmask(nil).All() {
+		t.Fatal("empty bitmask")
+	}
+}
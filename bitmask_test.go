@@ -1,6 +1,11 @@
 package numa
 
 import (
+	"encoding/json"
+	"flag"
+	"io"
+	"os"
+	"path/filepath"
 	"strings"
 	"testing"
 
@@ -49,3 +54,265 @@ func TestBitmask(t *testing.T) {
 		}
 	}
 }
+
+func TestBitmaskSetOperations(t *testing.T) {
+	var (
+		assert = require.New(t)
+		short  = NewBitmask(64)
+		long   = NewBitmask(256)
+	)
+	for _, i := range []int{1, 3, 5, 63} {
+		short.Set(i, true)
+	}
+	for _, i := range []int{3, 63, 64, 200} {
+		long.Set(i, true)
+	}
+
+	bits := func(m Bitmask) (s []int) {
+		for i := 0; i < m.Len(); i++ {
+			if m.Get(i) {
+				s = append(s, i)
+			}
+		}
+		return
+	}
+	for _, v := range []struct {
+		a, b Bitmask
+	}{{short, long}, {long, short}} {
+		assert.Len(v.a.And(v.b), 4)
+		assert.Equal([]int{3, 63}, bits(v.a.And(v.b)))
+		assert.Equal([]int{1, 3, 5, 63, 64, 200}, bits(v.a.Or(v.b)))
+		assert.Equal([]int{1, 5, 64, 200}, bits(v.a.Xor(v.b)))
+		assert.True(v.a.Intersects(v.b))
+		assert.False(v.a.Equal(v.b))
+		assert.False(v.a.IsSubsetOf(v.b))
+	}
+	assert.Equal([]int{1, 5}, bits(short.AndNot(long)))
+	assert.Equal([]int{64, 200}, bits(long.AndNot(short)))
+
+	assert.True(short.And(long).IsSubsetOf(short))
+	assert.True(short.And(long).IsSubsetOf(long))
+	assert.True(short.IsSubsetOf(short.Or(long)))
+	assert.True(short.Equal(short.Or(NewBitmask(1024))))
+	assert.True(NewBitmask(1024).Equal(nil))
+	assert.True(Bitmask(nil).IsSubsetOf(short))
+	assert.False(short.Intersects(long.AndNot(short)))
+	assert.False(short.Intersects(nil))
+
+	assert.True(NewBitmask(128).IsEmpty())
+	assert.True(Bitmask(nil).IsEmpty())
+	assert.False(short.IsEmpty())
+	assert.True(short.Xor(short).IsEmpty())
+
+	assert.Equal([]int{1, 3, 5, 63}, bits(short), "operands are unchanged")
+}
+
+func TestBitmaskIteration(t *testing.T) {
+	assert := require.New(t)
+	for _, n := range []int{0, 64, 200, 8192} {
+		mask := NewBitmask(n)
+		assert.Equal(-1, mask.First())
+		assert.Equal(-1, mask.Last())
+		assert.Equal(-1, mask.NextSet(0))
+		mask.ForEach(func(int) bool {
+			t.Fatal("empty bitmask")
+			return false
+		})
+	}
+
+	var (
+		mask = NewBitmask(8192)
+		set  = []int{0, 1, 63, 64, 127, 1000, 8191}
+		got  []int
+	)
+	for _, i := range set {
+		mask.Set(i, true)
+	}
+	assert.Equal(0, mask.First())
+	assert.Equal(8191, mask.Last())
+	assert.Equal(0, mask.NextSet(-5))
+	assert.Equal(63, mask.NextSet(2))
+	assert.Equal(64, mask.NextSet(64))
+	assert.Equal(1000, mask.NextSet(128))
+	assert.Equal(-1, mask.NextSet(8192))
+	assert.Equal(-1, mask.NextSet(1<<20))
+	mask.ForEach(func(i int) bool {
+		got = append(got, i)
+		return true
+	})
+	assert.Equal(set, got)
+
+	got = got[:0]
+	mask.ForEach(func(i int) bool {
+		got = append(got, i)
+		return i < 63
+	})
+	assert.Equal([]int{0, 1, 63}, got)
+}
+
+func TestBitmaskList(t *testing.T) {
+	assert := require.New(t)
+	var tt = []struct {
+		s    string
+		bits []int
+		list string
+	}{
+		{"", nil, ""},
+		{"0", []int{0}, "0"},
+		{"0-3,8-11,64", []int{0, 1, 2, 3, 8, 9, 10, 11, 64}, "0-3,8-11,64"},
+		{"1,3-4,5\n", []int{1, 3, 4, 5}, "1,3-5"},
+		{"7-7", []int{7}, "7"},
+		{"0-15:2/4", []int{0, 1, 4, 5, 8, 9, 12, 13}, "0-1,4-5,8-9,12-13"},
+		{"0-31:1/8,100", []int{0, 8, 16, 24, 100}, "0,8,16,24,100"},
+		{"0-6:3/4", []int{0, 1, 2, 4, 5, 6}, "0-2,4-6"},
+		{"2-9:0/3", nil, ""},
+		{"5:1/1", []int{5}, "5"},
+	}
+	for _, v := range tt {
+		mask, err := ParseList(v.s)
+		assert.NoError(err, v.s)
+		var bits []int
+		mask.ForEach(func(i int) bool {
+			bits = append(bits, i)
+			return true
+		})
+		assert.Equal(v.bits, bits, v.s)
+		assert.Equal(v.list, mask.ListString(), v.s)
+		if len(v.bits) != 0 {
+			assert.True(mask.Len() > v.bits[len(v.bits)-1])
+		}
+
+		again, err := ParseList(mask.ListString())
+		assert.NoError(err)
+		assert.True(mask.Equal(again), v.s)
+	}
+
+	for _, s := range []string{
+		",", "0,", ",1", "a", "1-", "-1", "3-1", "1--2", "0-3:", "0-3:1",
+		"0-3:/2", "0-3:1/", "0-3:3/2", "0-3:1/0", "0-3:1/2/4", "0 1", "+1",
+		"99999999999", "0-2147483647", "65536",
+	} {
+		_, err := ParseList(s)
+		assert.Error(err, s)
+	}
+
+	mask, err := ParseList("65535")
+	assert.NoError(err)
+	assert.Equal(65536, mask.Len())
+	mask, err = ParseListN("0-63", 64)
+	assert.NoError(err)
+	assert.Equal(64, mask.OnesCount())
+	_, err = ParseListN("0-64", 64)
+	assert.Error(err)
+	_, err = ParseListN("0-255:1/128", 128)
+	assert.Error(err)
+}
+
+func TestBitmaskHexMask(t *testing.T) {
+	assert := require.New(t)
+	var tt = []struct {
+		s     string
+		nbits int
+		bits  []int
+	}{
+		{"1", 1, []int{0}},
+		{"ff", 8, []int{0, 1, 2, 3, 4, 5, 6, 7}},
+		{"000f,ff000fff", 48, []int{0, 11, 24, 35}},
+		{"fff0,00fff000", 48, []int{12, 23, 36, 47}},
+		{"00000000,00000000,00000001,00000000", 128, []int{32}},
+		{"80000000,00000000,00000000", 96, []int{95}},
+		{"0", 4, nil},
+	}
+	for _, v := range tt {
+		mask, err := ParseHexMask(v.s + "\n")
+		assert.NoError(err, v.s)
+		assert.True(mask.Len() >= v.nbits)
+		for _, i := range v.bits {
+			assert.True(mask.Get(i), "%s bit %d", v.s, i)
+		}
+		if len(v.bits) == 0 {
+			assert.True(mask.IsEmpty())
+		}
+		assert.Equal(v.s, mask.HexMaskN(v.nbits))
+	}
+
+	mask := NewBitmask(64)
+	mask.Set(0, true)
+	mask.Set(33, true)
+	assert.Equal("00000002,00000001", mask.HexMask())
+	assert.Equal("", Bitmask(nil).HexMask())
+
+	// HexMask pads to b.Len(), only HexMaskN round-trips the kernel format.
+	mask, err := ParseHexMask("ffff,ffffffff")
+	assert.NoError(err)
+	assert.Equal("0000ffff,ffffffff", mask.HexMask())
+	assert.Equal("ffff,ffffffff", mask.HexMaskN(48))
+
+	for _, s := range []string{"", ",", "ff,", ",ff", "fg", "123456789", "0x1", "-1", "ff ff"} {
+		_, err := ParseHexMask(s)
+		assert.Error(err, s)
+	}
+
+	// round-trip with the cpumap of kernel
+	files, err := filepath.Glob("testdata/*/sys/devices/system/node/node*/cpumap")
+	assert.NoError(err)
+	assert.NotEmpty(files)
+	for _, f := range files {
+		d, err := os.ReadFile(f)
+		assert.NoError(err)
+		possible, err := os.ReadFile(filepath.Join(f, "../../../cpu/possible"))
+		assert.NoError(err)
+		pmask, err := ParseList(string(possible))
+		assert.NoError(err)
+		mask, err := ParseHexMask(string(d))
+		assert.NoError(err)
+		assert.Equal(strings.TrimSpace(string(d)), mask.HexMaskN(pmask.Last()+1), f)
+	}
+}
+
+func TestBitmaskEncoding(t *testing.T) {
+	assert := require.New(t)
+	mask, err := ParseList("0-3,7,64")
+	assert.NoError(err)
+
+	text, err := mask.MarshalText()
+	assert.NoError(err)
+	assert.Equal("0-3,7,64", string(text))
+	var decoded Bitmask
+	assert.NoError(decoded.UnmarshalText(text))
+	assert.True(mask.Equal(decoded))
+	assert.Error(decoded.UnmarshalText([]byte("3-1")))
+
+	type plan struct {
+		CPUs  Bitmask  `json:"cpus"`
+		Nodes *Bitmask `json:"nodes"`
+		Empty Bitmask  `json:"empty"`
+	}
+	nodes := NewBitmask(2)
+	nodes.Set(1, true)
+	data, err := json.Marshal(plan{CPUs: mask, Nodes: &nodes})
+	assert.NoError(err)
+	assert.JSONEq(`{"cpus":"0-3,7,64","nodes":"1","empty":""}`, string(data))
+
+	var p plan
+	assert.NoError(json.Unmarshal(data, &p))
+	assert.True(mask.Equal(p.CPUs))
+	assert.True(nodes.Equal(*p.Nodes))
+	assert.True(p.Empty.IsEmpty())
+	assert.Error(json.Unmarshal([]byte(`{"cpus":"a"}`), &p))
+	assert.Error(json.Unmarshal([]byte(`{"cpus":1}`), &p))
+
+	var (
+		cpus Bitmask
+		fs   = flag.NewFlagSet("test", flag.ContinueOnError)
+	)
+	fs.SetOutput(io.Discard)
+	fs.Var(ListValue(&cpus), "cpus", "cpus")
+	assert.NoError(fs.Parse([]string{"--cpus=0-7"}))
+	assert.Equal(8, cpus.OnesCount())
+	assert.Equal("0-7", fs.Lookup("cpus").Value.String())
+	assert.Equal(cpus, fs.Lookup("cpus").Value.(flag.Getter).Get())
+	assert.Error(fs.Parse([]string{"--cpus=7-0"}))
+	assert.Error(fs.Parse([]string{"--cpus=0-2147483647"}))
+	assert.Error(json.Unmarshal([]byte(`{"cpus":"0-2147483647"}`), &p))
+}
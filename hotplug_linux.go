@@ -0,0 +1,109 @@
+//go:build linux
+// +build linux
+
+package numa
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"os"
+	"sync"
+	"syscall"
+)
+
+// Watcher listens the kernel uevents of cpu, memory and node subsystems and
+// refreshes the system topology when they changed.
+type Watcher struct {
+	r    io.ReadCloser
+	fn   func(*Topology, error)
+	once sync.Once
+	done chan struct{}
+}
+
+// WatchTopology starts a Watcher, which listens the kernel uevents
+// (NETLINK_KOBJECT_UEVENT) of cpu, memory and node subsystems, and calls
+// Refresh when any of them arrived. The fn is called with the new topology
+// if it is different with the previous one, or with the error of Refresh.
+// The fn is called in a dedicated goroutine, one at a time, and must not call
+// Close of the returned Watcher. If the socket failed to read, the fn is
+// called with the error, and the watcher stops, except the ENOBUFS of a burst
+// of events, after which the topology is refreshed.
+func WatchTopology(fn func(*Topology, error)) (*Watcher, error) {
+	fd, err := syscall.Socket(syscall.AF_NETLINK,
+		syscall.SOCK_RAW|syscall.SOCK_CLOEXEC|syscall.SOCK_NONBLOCK,
+		syscall.NETLINK_KOBJECT_UEVENT)
+	if err != nil {
+		return nil, os.NewSyscallError("socket", err)
+	}
+	// The group 1 is the kernel uevent multicast group.
+	sa := &syscall.SockaddrNetlink{Family: syscall.AF_NETLINK, Groups: 1}
+	if err = syscall.Bind(fd, sa); err != nil {
+		syscall.Close(fd)
+		return nil, os.NewSyscallError("bind", err)
+	}
+	w := &Watcher{
+		r:    os.NewFile(uintptr(fd), "uevent"),
+		fn:   fn,
+		done: make(chan struct{}),
+	}
+	go w.loop()
+	return w, nil
+}
+
+// Close stops the watcher and waits for the running callback returned. It
+// must not be called from the callback, which deadlocks; close the watcher in
+// another goroutine instead.
+func (w *Watcher) Close() (err error) {
+	w.once.Do(func() {
+		err = w.r.Close()
+		<-w.done
+	})
+	return
+}
+
+func (w *Watcher) loop() {
+	defer close(w.done)
+	buf := make([]byte, 64<<10)
+	for {
+		n, err := w.r.Read(buf)
+		switch {
+		case err == nil:
+			if !ishotplugevent(buf[:n]) {
+				continue
+			}
+		case errors.Is(err, syscall.ENOBUFS):
+			// The receive buffer overflowed by a burst of events, such as
+			// onlining many memory blocks, so some events are lost. Refresh
+			// anyway and keep reading.
+		case errors.Is(err, os.ErrClosed):
+			return // closed by Close
+		default:
+			w.fn(nil, err)
+			return
+		}
+		prev := SystemTopology()
+		if err := Refresh(); err != nil {
+			w.fn(nil, err)
+		} else if t := SystemTopology(); !t.Equal(prev) {
+			w.fn(t, nil)
+		}
+	}
+}
+
+// ishotplugevent reports whether the uevent message belongs to the cpu,
+// memory or node subsystem. The message of kernel is formatted as
+// "action@devpath\0KEY=VALUE\0KEY=VALUE...".
+func ishotplugevent(msg []byte) bool {
+	fields := bytes.Split(msg, []byte{0})
+	if len(fields) == 0 || !bytes.Contains(fields[0], []byte("@")) {
+		return false
+	}
+	for _, field := range fields[1:] {
+		switch string(field) {
+		case "SUBSYSTEM=cpu", "SUBSYSTEM=memory", "SUBSYSTEM=node":
+			return true
+		}
+	}
+	return false
+}
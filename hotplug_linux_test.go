@@ -0,0 +1,79 @@
+package numa
+
+import (
+	"errors"
+	"os"
+	"strings"
+	"syscall"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestIsHotplugEvent(t *testing.T) {
+	var tt = []struct {
+		msg string
+		v   bool
+	}{
+		{"online@/devices/system/cpu/cpu3\x00ACTION=online\x00DEVPATH=/devices/system/cpu/cpu3\x00SUBSYSTEM=cpu\x00SEQNUM=4210\x00", true},
+		{"add@/devices/system/memory/memory40\x00ACTION=add\x00DEVPATH=/devices/system/memory/memory40\x00SUBSYSTEM=memory\x00", true},
+		{"add@/devices/system/node/node1\x00ACTION=add\x00SUBSYSTEM=node\x00", true},
+		{"add@/devices/virtual/net/veth0\x00ACTION=add\x00SUBSYSTEM=net\x00", false},
+		{"libudev\x00SUBSYSTEM=cpu", false},
+		{"", false},
+	}
+	for _, v := range tt {
+		require.Equal(t, v.v, ishotplugevent([]byte(v.msg)), strings.Replace(v.msg, "\x00", " ", -1))
+	}
+}
+
+func TestWatchTopology(t *testing.T) {
+	w, err := WatchTopology(func(*Topology, error) {})
+	if err != nil {
+		t.Skip("uevent is not available: ", err)
+	}
+	require.NoError(t, w.Close())
+	require.NoError(t, w.Close())
+}
+
+// fakeuevent returns the errors of errs one by one by Read.
+type fakeuevent struct{ errs []error }
+
+func (f *fakeuevent) Read(b []byte) (int, error) {
+	if len(f.errs) == 0 {
+		return 0, os.ErrClosed
+	}
+	err := f.errs[0]
+	f.errs = f.errs[1:]
+	return 0, err
+}
+
+func (f *fakeuevent) Close() error { return nil }
+
+func TestWatcherReadError(t *testing.T) {
+	var tt = []struct {
+		errs []error
+		want []error
+	}{
+		// The ENOBUFS refreshes the topology and keeps reading, the topology
+		// is unchanged, so fn is not called.
+		{[]error{&os.PathError{Op: "read", Path: "uevent", Err: syscall.ENOBUFS}}, nil},
+		// The other errors are reported and stop the watcher.
+		{[]error{&os.PathError{Op: "read", Path: "uevent", Err: syscall.EBADF}, syscall.ENOBUFS},
+			[]error{syscall.EBADF}},
+		{nil, nil},
+	}
+	for _, v := range tt {
+		var got []error
+		w := &Watcher{
+			r:    &fakeuevent{errs: v.errs},
+			fn:   func(_ *Topology, err error) { got = append(got, err) },
+			done: make(chan struct{}),
+		}
+		w.loop()
+		require.Len(t, got, len(v.want))
+		for i := range got {
+			require.True(t, errors.Is(got[i], v.want[i]), "%v", got[i])
+		}
+	}
+}
@@ -0,0 +1,20 @@
+//go:build !linux
+// +build !linux
+
+package numa
+
+import "syscall"
+
+// Watcher listens the kernel uevents of cpu, memory and node subsystems and
+// refreshes the system topology when they changed.
+type Watcher struct{}
+
+// WatchTopology is unsupported on non-linux platform.
+func WatchTopology(fn func(*Topology, error)) (*Watcher, error) {
+	return nil, syscall.ENOSYS
+}
+
+// Close stops the watcher.
+func (w *Watcher) Close() error {
+	return nil
+}
@@ -0,0 +1,173 @@
+package numa
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// MemInfo is the memory usage of a NUMA node, which parsed from
+// /sys/devices/system/node/nodeN/meminfo. All fields are in bytes except the
+// HugePages ones, which are counts of huge pages. The fields which absent in
+// current kernel are zero.
+type MemInfo struct {
+	MemTotal       uint64
+	MemFree        uint64
+	MemUsed        uint64
+	SwapCached     uint64
+	Active         uint64
+	Inactive       uint64
+	ActiveAnon     uint64
+	InactiveAnon   uint64
+	ActiveFile     uint64
+	InactiveFile   uint64
+	Unevictable    uint64
+	Mlocked        uint64
+	HighTotal      uint64
+	HighFree       uint64
+	LowTotal       uint64
+	LowFree        uint64
+	Dirty          uint64
+	Writeback      uint64
+	FilePages      uint64
+	Mapped         uint64
+	AnonPages      uint64
+	Shmem          uint64
+	KernelStack    uint64
+	PageTables     uint64
+	SecPageTables  uint64
+	NFSUnstable    uint64
+	Bounce         uint64
+	WritebackTmp   uint64
+	KReclaimable   uint64
+	Slab           uint64
+	SReclaimable   uint64
+	SUnreclaim     uint64
+	AnonHugePages  uint64
+	ShmemHugePages uint64
+	ShmemPmdMapped uint64
+	FileHugePages  uint64
+	FilePmdMapped  uint64
+	Unaccepted     uint64
+	HugePagesTotal uint64
+	HugePagesFree  uint64
+	HugePagesSurp  uint64
+}
+
+func (m *MemInfo) field(name string) *uint64 {
+	switch name {
+	case "MemTotal":
+		return &m.MemTotal
+	case "MemFree":
+		return &m.MemFree
+	case "MemUsed":
+		return &m.MemUsed
+	case "SwapCached":
+		return &m.SwapCached
+	case "Active":
+		return &m.Active
+	case "Inactive":
+		return &m.Inactive
+	case "Active(anon)":
+		return &m.ActiveAnon
+	case "Inactive(anon)":
+		return &m.InactiveAnon
+	case "Active(file)":
+		return &m.ActiveFile
+	case "Inactive(file)":
+		return &m.InactiveFile
+	case "Unevictable":
+		return &m.Unevictable
+	case "Mlocked":
+		return &m.Mlocked
+	case "HighTotal":
+		return &m.HighTotal
+	case "HighFree":
+		return &m.HighFree
+	case "LowTotal":
+		return &m.LowTotal
+	case "LowFree":
+		return &m.LowFree
+	case "Dirty":
+		return &m.Dirty
+	case "Writeback":
+		return &m.Writeback
+	case "FilePages":
+		return &m.FilePages
+	case "Mapped":
+		return &m.Mapped
+	case "AnonPages":
+		return &m.AnonPages
+	case "Shmem":
+		return &m.Shmem
+	case "KernelStack":
+		return &m.KernelStack
+	case "PageTables":
+		return &m.PageTables
+	case "SecPageTables":
+		return &m.SecPageTables
+	case "NFS_Unstable":
+		return &m.NFSUnstable
+	case "Bounce":
+		return &m.Bounce
+	case "WritebackTmp":
+		return &m.WritebackTmp
+	case "KReclaimable":
+		return &m.KReclaimable
+	case "Slab":
+		return &m.Slab
+	case "SReclaimable":
+		return &m.SReclaimable
+	case "SUnreclaim":
+		return &m.SUnreclaim
+	case "AnonHugePages":
+		return &m.AnonHugePages
+	case "ShmemHugePages":
+		return &m.ShmemHugePages
+	case "ShmemPmdMapped":
+		return &m.ShmemPmdMapped
+	case "FileHugePages":
+		return &m.FileHugePages
+	case "FilePmdMapped":
+		return &m.FilePmdMapped
+	case "Unaccepted":
+		return &m.Unaccepted
+	case "HugePages_Total":
+		return &m.HugePagesTotal
+	case "HugePages_Free":
+		return &m.HugePagesFree
+	case "HugePages_Surp":
+		return &m.HugePagesSurp
+	}
+	return nil
+}
+
+// NodeMemInfo returns the memory usage of given node.
+func (t *Topology) NodeMemInfo(node int) (info MemInfo, err error) {
+	fname := fmt.Sprintf("sys/devices/system/node/node%d/meminfo", node)
+	d, err := t.readfile(fname)
+	if err != nil {
+		return
+	}
+	// Node 0 MemTotal:        4554488 kB
+	// Node 0 HugePages_Total:     0
+	for _, line := range strings.Split(string(d), "\n") {
+		tokens := strings.Fields(line)
+		if len(tokens) < 4 || tokens[0] != "Node" {
+			continue
+		}
+		p := info.field(strings.TrimSuffix(tokens[2], ":"))
+		if p == nil {
+			continue
+		}
+		v, err := strconv.ParseUint(tokens[3], 10, 64)
+		if err != nil {
+			return MemInfo{}, fmt.Errorf("invalid line %q in %s: %v", line, fname, err)
+		}
+		if len(tokens) > 4 && tokens[4] == "kB" {
+			v *= 1024
+		}
+		*p = v
+	}
+	return
+}
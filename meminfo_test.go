@@ -0,0 +1,54 @@
+package numa
+
+import (
+	"runtime"
+	"syscall"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestTopologyNodeMemInfo(t *testing.T) {
+	var (
+		assert = require.New(t)
+		topo   = loadTestTopology(t, "dual-socket")
+	)
+	info, err := topo.NodeMemInfo(0)
+	assert.NoError(err)
+	assert.Equal(uint64(100663296*1024), info.MemTotal)
+	assert.Equal(uint64(75497472*1024), info.MemFree)
+	assert.Equal(uint64(2536603*1024), info.Active)
+	assert.Equal(uint64(1318319*1024), info.ActiveAnon)
+	assert.Equal(uint64(1218284*1024), info.ActiveFile)
+	assert.Equal(uint64(435935*1024), info.Shmem)
+	assert.Equal(uint64(2769033*1024), info.KReclaimable)
+	assert.Equal(uint64(3469723*1024), info.Slab)
+	assert.Equal(uint64(512), info.HugePagesTotal)
+	assert.Equal(uint64(256), info.HugePagesFree)
+	assert.Equal(uint64(0), info.HugePagesSurp)
+	assert.Equal(info.Active, info.ActiveAnon+info.ActiveFile)
+
+	_, err = topo.NodeMemInfo(2)
+	assert.Error(err)
+
+	info, err = loadTestTopology(t, "sparse-memoryless").NodeMemInfo(2)
+	assert.NoError(err)
+	assert.Equal(MemInfo{}, info)
+}
+
+func TestNodeMemInfo(t *testing.T) {
+	assert := require.New(t)
+	NodeMask().ForEach(func(node int) bool {
+		info, err := NodeMemInfo(node)
+		// The meminfo is read from sysfs on linux, which does not depend on
+		// the NUMA syscalls.
+		if runtime.GOOS != "linux" {
+			assert.Equal(syscall.ENOSYS, err, "node %d", node)
+			return true
+		}
+		assert.NoError(err, "node %d", node)
+		// The MemTotal of a memory-less node is zero.
+		assert.True(info.MemTotal >= info.MemFree, "node %d", node)
+		return true
+	})
+}
@@ -0,0 +1,66 @@
+package numa
+
+import (
+	"errors"
+	"fmt"
+	"syscall"
+)
+
+// PageError is the error of a page which failed to move.
+type PageError struct {
+	// Addr is the address of the page.
+	Addr uintptr
+	// Err is the errno reported by move_pages(2), e.g. EBUSY when the page
+	// is busy or locked, ENOMEM when the target node has no memory.
+	Err syscall.Errno
+}
+
+func (e *PageError) Error() string {
+	return fmt.Sprintf("move page %#x: %v", e.Addr, e.Err)
+}
+
+// Unwrap returns the errno of this error.
+func (e *PageError) Unwrap() error { return e.Err }
+
+// MigrateError is the error of MigrateSlice, which contains the pages failed
+// to move.
+type MigrateError []*PageError
+
+func (e MigrateError) Error() string {
+	if len(e) == 0 {
+		return "no page failed to move"
+	}
+	return fmt.Sprintf("%d pages failed to move, first: %v", len(e), e[0])
+}
+
+// Is reports whether any failed page matches target, so errors.Is(err,
+// syscall.EBUSY) works before Go 1.20, which does not follow Unwrap() []error.
+func (e MigrateError) Is(target error) bool {
+	for _, err := range e {
+		if errors.Is(err, target) {
+			return true
+		}
+	}
+	return false
+}
+
+// As finds the first failed page which matches target like Is, e.g.
+// errors.As(err, &pageErr) sets the first *PageError.
+func (e MigrateError) As(target interface{}) bool {
+	for _, err := range e {
+		if errors.As(err, target) {
+			return true
+		}
+	}
+	return false
+}
+
+// Unwrap returns the errors of all failed pages, which is used by errors.Is
+// and errors.As since Go 1.20.
+func (e MigrateError) Unwrap() []error {
+	errs := make([]error, len(e))
+	for i, err := range e {
+		errs[i] = err
+	}
+	return errs
+}
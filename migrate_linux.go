@@ -0,0 +1,176 @@
+//go:build linux
+// +build linux
+
+package numa
+
+import (
+	"fmt"
+	"os"
+	"syscall"
+	"unsafe"
+)
+
+// MovePages moves the given pages of the process pid to the memory of the
+// given nodes, nodes[i] is the target of pages[i]. If pid is zero, then the
+// calling process is used. If nodes is nil, MovePages does not move any page
+// but queries the node of each page. The flags can be MPOL_MF_MOVE, which
+// only moves the pages used exclusively by the process, or MPOL_MF_MOVE_ALL,
+// which moves the pages shared with other processes also.
+//
+// The status[i] is the node id of pages[i] where it resides after the call,
+// or a negative errno if failed, e.g. -EBUSY, -ENOMEM, -ENOENT.
+func MovePages(pid int, pages []unsafe.Pointer, nodes []int, flags int) (status []int, err error) {
+	if nodes != nil && len(nodes) != len(pages) {
+		return nil, syscall.EINVAL
+	}
+	var (
+		addrs = make([]uintptr, len(pages))
+		nn    []int32
+		ss    = make([]int32, len(pages))
+	)
+	for i, p := range pages {
+		addrs[i] = uintptr(p)
+	}
+	if nodes != nil {
+		nn = make([]int32, len(nodes))
+		for i, n := range nodes {
+			nn[i] = int32(n)
+		}
+	}
+	if err = movepages(pid, addrs, nn, ss, flags); err != nil {
+		return nil, err
+	}
+	status = make([]int, len(ss))
+	for i, s := range ss {
+		status[i] = int(s)
+	}
+	return status, nil
+}
+
+// MigrateSlice moves the pages of b to the given node. The pages which have
+// not been touched are skipped. If some pages failed to move, a MigrateError
+// is returned.
+func MigrateSlice(b []byte, node int) error {
+	if len(b) == 0 {
+		return nil
+	}
+	if node < 0 || node > MaxPossibleNodeID() {
+		return fmt.Errorf("invalided node %d", node)
+	}
+	var (
+		pages  = pagesof(b)
+		nodes  = make([]int32, len(pages))
+		status = make([]int32, len(pages))
+		merr   MigrateError
+	)
+	for i := range nodes {
+		nodes[i] = int32(node)
+	}
+	if err := movepages(0, pages, nodes, status, MPOL_MF_MOVE); err != nil {
+		return err
+	}
+	for i, s := range status {
+		if s < 0 && syscall.Errno(-s) != syscall.ENOENT {
+			merr = append(merr, &PageError{Addr: pages[i], Err: syscall.Errno(-s)})
+		}
+	}
+	if len(merr) != 0 {
+		return merr
+	}
+	return nil
+}
+
+// NodeOfAddress returns the node id of the page which contains the address p.
+// The page must have been touched, otherwise ENOENT returned.
+func NodeOfAddress(p unsafe.Pointer) (int, error) {
+	pages := []uintptr{uintptr(p)}
+	status := make([]int32, 1)
+	if err := movepages(0, pages, nil, status, 0); err != nil {
+		return 0, err
+	}
+	if status[0] < 0 {
+		return 0, syscall.Errno(-status[0])
+	}
+	return int(status[0]), nil
+}
+
+// PageNodes returns the node ids of every page of b by move_pages(2) in query
+// mode. The status of the page which failed to query is a negative errno,
+// e.g. -ENOENT for the page which has not been touched.
+func PageNodes(b []byte) ([]int, error) {
+	if len(b) == 0 {
+		return nil, nil
+	}
+	pages := pagesof(b)
+	status := make([]int32, len(pages))
+	if err := movepages(0, pages, nil, status, 0); err != nil {
+		return nil, err
+	}
+	nodes := make([]int, len(status))
+	for i, s := range status {
+		nodes[i] = int(s)
+	}
+	return nodes, nil
+}
+
+// MigratePages moves all pages of the process pid in the nodes from to the
+// nodes to, it wraps migrate_pages(2). If pid is zero, then the calling process
+// is used. The bitmasks are usually sized by NodePossibleCount(), like the one
+// of SetMemPolicy. It returns the number of pages that could not be moved.
+// @numa_migrate_pages
+func MigratePages(pid int, from, to Bitmask) (notMoved int, err error) {
+	// the kernel reads both masks with the same maxnode.
+	if len(from) != len(to) {
+		n := len(from)
+		if len(to) > n {
+			n = len(to)
+		}
+		from = append(from[:len(from):len(from)], make(Bitmask, n-len(from))...)
+		to = append(to[:len(to):len(to)], make(Bitmask, n-len(to))...)
+	}
+	n, _, errno := syscall.Syscall6(_SYS_MIGRATE_PAGES, uintptr(pid),
+		uintptr(from.Len()), uintptr(sliceptr(from)), uintptr(sliceptr(to)), 0, 0)
+	if errno != 0 {
+		return 0, errno
+	}
+	return int(n), nil
+}
+
+// pagesof returns the addresses of every page which b covers.
+func pagesof(b []byte) []uintptr {
+	var (
+		pagesize = uintptr(os.Getpagesize())
+		start    = uintptr(unsafe.Pointer(&b[0])) &^ (pagesize - 1)
+		end      = uintptr(unsafe.Pointer(&b[0])) + uintptr(len(b))
+		pages    = make([]uintptr, 0, (end-start+pagesize-1)/pagesize)
+	)
+	for p := start; p < end; p += pagesize {
+		pages = append(pages, p)
+	}
+	return pages
+}
+
+// movepages wraps move_pages(2), the nodes is nil in query mode.
+func movepages(pid int, pages []uintptr, nodes, status []int32, flags int) error {
+	if len(pages) == 0 {
+		return nil
+	}
+	// The pointers are converted in the call expression, so the slices are
+	// kept alive and not moved during the syscall.
+	_, _, errno := syscall.Syscall6(syscall.SYS_MOVE_PAGES, uintptr(pid),
+		uintptr(len(pages)), uintptr(sliceptr(pages)),
+		uintptr(sliceptr(nodes)), uintptr(sliceptr(status)), uintptr(flags))
+	if errno != 0 {
+		return errno
+	}
+	return nil
+}
+
+// sliceptr returns the pointer of the first element of s, or nil if s is
+// empty.
+func sliceptr[T any](s []T) unsafe.Pointer {
+	if len(s) == 0 {
+		return nil
+	}
+	return unsafe.Pointer(&s[0])
+}
@@ -0,0 +1,136 @@
+package numa
+
+import (
+	"errors"
+	"os"
+	"syscall"
+	"testing"
+	"unsafe"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestPageNodes(t *testing.T) {
+	if !Available() {
+		t.Skip("skip by not available")
+	}
+	var (
+		assert   = require.New(t)
+		pagesize = os.Getpagesize()
+	)
+	memorynodes().ForEach(func(node int) bool {
+		b, err := AllocOnNode(4*pagesize, node)
+		assert.NoError(err)
+		defer Free(b)
+
+		_, err = NodeOfAddress(unsafe.Pointer(&b[0]))
+		assert.Equal(syscall.ENOENT, err)
+
+		b[0], b[2*pagesize] = 1, 1
+		n, err := NodeOfAddress(unsafe.Pointer(&b[2*pagesize+100]))
+		assert.NoError(err)
+		assert.Equal(node, n)
+
+		nodes, err := PageNodes(b)
+		assert.NoError(err)
+		assert.Equal([]int{node, -int(syscall.ENOENT), node, -int(syscall.ENOENT)}, nodes)
+
+		nodes, err = PageNodes(b[pagesize-1 : pagesize+1])
+		assert.NoError(err)
+		assert.Len(nodes, 2)
+		return true
+	})
+
+	nodes, err := PageNodes(nil)
+	assert.NoError(err)
+	assert.Empty(nodes)
+}
+
+func TestMovePages(t *testing.T) {
+	if !Available() {
+		t.Skip("skip by not available")
+	}
+	var (
+		assert   = require.New(t)
+		pagesize = os.Getpagesize()
+		nodemask = memorynodes()
+		first    = nodemask.First()
+		last     = nodemask.Last()
+	)
+	b, err := AllocOnNode(3*pagesize, first)
+	assert.NoError(err)
+	defer Free(b)
+	b[0], b[pagesize] = 1, 1
+
+	pages := []unsafe.Pointer{unsafe.Pointer(&b[0]), unsafe.Pointer(&b[pagesize]), unsafe.Pointer(&b[2*pagesize])}
+	status, err := MovePages(0, pages, nil, 0)
+	assert.NoError(err)
+	assert.Equal([]int{first, first, -int(syscall.ENOENT)}, status)
+
+	status, err = MovePages(0, pages, []int{last, last, last}, MPOL_MF_MOVE)
+	assert.NoError(err)
+	assert.Equal([]int{last, last, -int(syscall.ENOENT)}, status)
+
+	assert.NoError(MigrateSlice(b, first))
+	nodes, err := PageNodes(b)
+	assert.NoError(err)
+	assert.Equal([]int{first, first, -int(syscall.ENOENT)}, nodes)
+
+	_, err = MovePages(0, pages, []int{0}, MPOL_MF_MOVE)
+	assert.Equal(syscall.EINVAL, err)
+	assert.Error(MigrateSlice(b, -1))
+	assert.NoError(MigrateSlice(nil, first))
+}
+
+func TestMigrateError(t *testing.T) {
+	assert := require.New(t)
+	err := error(MigrateError{
+		{Addr: 0x1000, Err: syscall.EBUSY},
+		{Addr: 0x2000, Err: syscall.ENOMEM},
+	})
+	assert.Contains(err.Error(), "2 pages")
+	assert.True(errors.Is(err, syscall.EBUSY))
+	assert.True(errors.Is(err, syscall.ENOMEM))
+	assert.False(errors.Is(err, syscall.EFAULT))
+	var perr *PageError
+	assert.True(errors.As(err, &perr))
+	assert.Equal(uintptr(0x1000), perr.Addr)
+
+	merr := MigrateError{{Addr: 0x3000, Err: syscall.EACCES}}
+	assert.True(merr.Is(syscall.EACCES))
+	assert.False(merr.Is(syscall.EBUSY))
+	assert.True(merr.As(&perr))
+	assert.Equal(uintptr(0x3000), perr.Addr)
+	var errno syscall.Errno
+	assert.True(merr.As(&errno))
+	assert.Equal(syscall.EACCES, errno)
+
+	assert.NotEmpty(MigrateError{}.Error())
+	assert.False(MigrateError{}.Is(syscall.EBUSY))
+	assert.False(MigrateError{}.As(&perr))
+}
+
+func TestMigratePages(t *testing.T) {
+	if !Available() {
+		t.Skip("skip by not available")
+	}
+	var (
+		assert   = require.New(t)
+		nodemask = memorynodes()
+		from     = NewBitmask(NodePossibleCount())
+		to       = NewBitmask(NodePossibleCount())
+	)
+	from.Set(nodemask.Last(), true)
+	to.Set(nodemask.First(), true)
+	n, err := MigratePages(0, from, to)
+	assert.NoError(err)
+	assert.True(n >= 0)
+
+	// the masks of different length
+	n, err = MigratePages(0, from, to[:1])
+	assert.NoError(err)
+	assert.True(n >= 0)
+
+	_, err = MigratePages(-1, from, to)
+	assert.Error(err)
+}
@@ -0,0 +1,36 @@
+//go:build !linux
+// +build !linux
+
+package numa
+
+import (
+	"syscall"
+	"unsafe"
+)
+
+// NodeOfAddress returns the node id of the page which contains the address p.
+func NodeOfAddress(p unsafe.Pointer) (int, error) {
+	return 0, syscall.ENOSYS
+}
+
+// PageNodes returns the node ids of every page of b.
+func PageNodes(b []byte) ([]int, error) {
+	return nil, syscall.ENOSYS
+}
+
+// MovePages moves the given pages of the process pid to the memory of the
+// given nodes.
+func MovePages(pid int, pages []unsafe.Pointer, nodes []int, flags int) (status []int, err error) {
+	return nil, syscall.ENOSYS
+}
+
+// MigrateSlice moves the pages of b to the given node.
+func MigrateSlice(b []byte, node int) error {
+	return syscall.ENOSYS
+}
+
+// MigratePages moves all pages of the process pid in the nodes from to the
+// nodes to.
+func MigratePages(pid int, from, to Bitmask) (notMoved int, err error) {
+	return 0, syscall.ENOSYS
+}
@@ -1,31 +1,21 @@
 package numa
 
 import (
+	"errors"
 	"fmt"
+	"sync/atomic"
 )
 
 var (
 	available bool
-	// The max possible node count, which represents the node count of local
-	// platform supporting.
-	// nnodemax =@nodemask_sz+1
-	nnodemax int
-	// The max configured(enabled/setuped) node, which represents the
-	// available node count of local platform.
-	// nconfigurednode =@maxconfigurednode+1
-	nconfigurednode int
-	// The max possible cpu count, which represents the cpu count of local
-	// platform supporting.
-	// ncpumax =@cpumask_sz+1
-	ncpumax int
-	// nconfiguredcpu =@maxconfiguredcpu
-	nconfiguredcpu int
-
-	memnodes  Bitmask
-	numanodes Bitmask
-
-	cpu2node map[int]int
-	node2cpu map[int]Bitmask
+
+	// ErrUnsupported is returned when a feature is not supported by current
+	// kernel.
+	ErrUnsupported = errors.New("numa: unsupported by current kernel")
+
+	// topology holds the *Topology of current platform, which all package
+	// level functions delegate to. It is swapped atomically by Refresh.
+	topology atomic.Value
 )
 
 const (
@@ -35,6 +25,14 @@ const (
 	MPOL_BIND
 	MPOL_INTERLEAVE
 	MPOL_LOCAL
+	// MPOL_PREFERRED_MANY since Linux 5.15
+	// Allocates memory from the nodes of nodemask preferably, falls back to
+	// other nodes if all of them are exhausted.
+	MPOL_PREFERRED_MANY
+	// MPOL_WEIGHTED_INTERLEAVE since Linux 6.9
+	// Interleaves the memory on the nodes of nodemask according to the
+	// weight of each node, see SetWeightedInterleaveWeight.
+	MPOL_WEIGHTED_INTERLEAVE
 	MPOL_MAX
 
 	// MPOL_F_STATIC_NODES since Linux 2.6.26
@@ -87,16 +85,36 @@ func Available() bool {
 	return available
 }
 
+// SystemTopology returns the NUMA topology of current platform, which the
+// package level functions delegate to.
+func SystemTopology() *Topology {
+	return topology.Load().(*Topology)
+}
+
+// Refresh re-reads the NUMA topology of current platform and swaps it in
+// atomically, so the cpus or memory which onlined after the process started
+// become visible to the package level functions. The concurrent readers see
+// either the old or the new topology. The topology is unchanged if an error
+// returned.
+func Refresh() error {
+	t, err := LoadSystemTopology()
+	if err != nil {
+		return err
+	}
+	topology.Store(t)
+	return nil
+}
+
 // MaxNodeID returns the max id of current configured NUMA nodes.
 // @numa_max_node_int
 func MaxNodeID() int {
-	return nconfigurednode - 1
+	return SystemTopology().MaxNodeID()
 }
 
 // MaxPossibleNodeID returns the max possible node id of this platform supported.
 // The possible node id always larger than max node id.
 func MaxPossibleNodeID() int {
-	return nnodemax - 1
+	return SystemTopology().MaxPossibleNodeID()
 }
 
 // NodeCount returns the count of current configured NUMA nodes.
@@ -109,29 +127,29 @@ func MaxPossibleNodeID() int {
 // nodes, cpusets...
 // @numa_num_configured_nodes
 func NodeCount() int {
-	return memnodes.OnesCount()
+	return SystemTopology().NodeCount()
 }
 
 // NodeMask returns the mask of current configured nodes.
 func NodeMask() Bitmask {
-	return memnodes.Clone()
+	return SystemTopology().NodeMask()
 }
 
 // NodePossibleCount returns the possible NUMA nodes count of current platform
 // supported.
 func NodePossibleCount() int {
-	return nnodemax
+	return SystemTopology().NodePossibleCount()
 }
 
 // CPUPossibleCount returns the possible cpu count of current platform supported.
 func CPUPossibleCount() int {
-	return ncpumax
+	return SystemTopology().CPUPossibleCount()
 }
 
 // CPUCount returns the current configured(enabled/detected) cpu count, which
 // is different with runtime.NumCPU().
 func CPUCount() int {
-	return nconfiguredcpu
+	return SystemTopology().CPUCount()
 }
 
 // RunningNodesMask return the bitmask of current process using NUMA nodes.
@@ -142,10 +160,7 @@ func RunningNodesMask() (Bitmask, error) {
 	if _, err := GetSchedAffinity(0, cpumask); err != nil {
 		return nil, err
 	}
-	for i := 0; i < cpumask.Len(); i++ {
-		if !cpumask.Get(i) {
-			continue
-		}
+	for i := cpumask.First(); i >= 0; i = cpumask.NextSet(i + 1) {
 		n, err := CPUToNode(i)
 		if err != nil {
 			return nil, err
@@ -167,43 +182,67 @@ func RunningCPUMask() (Bitmask, error) {
 // NodeToCPUMask returns the cpumask of given node id.
 // @numa_node_to_cpus_v2
 func NodeToCPUMask(node int) (Bitmask, error) {
-	if node > MaxPossibleNodeID() {
-		return nil, fmt.Errorf("node %d is out of range", node)
-	}
-	cpumask, ok := node2cpu[node]
-	if !ok {
-		return nil, fmt.Errorf("node %d not found", node)
-	}
-	return cpumask.Clone(), nil
+	return SystemTopology().NodeToCPUMask(node)
 }
 
 // CPUToNode returns the node id by given cpu id.
 func CPUToNode(cpu int) (int, error) {
-	node, ok := cpu2node[cpu]
-	if !ok {
-		return 0, fmt.Errorf("cpu %d not found", cpu)
-	}
-	return node, nil
+	return SystemTopology().CPUToNode(cpu)
+}
+
+// Distance returns the distance between node from and node to, which reported
+// by ACPI SLIT. The local distance is usually 10.
+// @numa_distance
+func Distance(from, to int) (int, error) {
+	return SystemTopology().Distance(from, to)
+}
+
+// DistanceMatrix returns a snapshot of the distances between all configured
+// nodes, which indexed by node id. The distance of absent node is 0.
+func DistanceMatrix() [][]int {
+	return SystemTopology().DistanceMatrix()
+}
+
+// NodesByDistance returns the nodes which have memory ordered by the distance
+// from the given node, nearest first, ties broken by node id. It likes the
+// zonelist of kernel and can be used as the fallback order when allocating
+// memory. The memory-less nodes are excluded by default, use AllNodesByDistance
+// to include them. It returns nil if the given node is unknown.
+func NodesByDistance(node int) []int {
+	return SystemTopology().NodesByDistance(node)
+}
+
+// AllNodesByDistance returns all configured nodes ordered by the distance from
+// the given node like NodesByDistance, the memory-less nodes are included, so
+// it can be used to find the nearest nodes of cpus rather than memory.
+func AllNodesByDistance(node int) []int {
+	return SystemTopology().AllNodesByDistance(node)
 }
 
 // RunOnNode set current process run on given node.
 // The special node -1 will set current process on all available nodes.
 // @numa_run_on_node
 func RunOnNode(node int) (err error) {
-	var cpumask Bitmask
+	cpumask, err := nodecpumask(node)
+	if err != nil {
+		return err
+	}
+	return SetSchedAffinity(0, cpumask)
+}
+
+// nodecpumask returns the cpumask of given node, the special node -1 means
+// all cpus.
+func nodecpumask(node int) (cpumask Bitmask, err error) {
 	switch {
 	case node == -1:
 		cpumask = NewBitmask(CPUPossibleCount())
 		cpumask.SetAll()
 	case node >= 0:
 		cpumask, err = NodeToCPUMask(node)
-		if err != nil {
-			return err
-		}
 	default:
-		return fmt.Errorf("invalided node %d", node)
+		err = fmt.Errorf("invalided node %d", node)
 	}
-	return SetSchedAffinity(0, cpumask)
+	return
 }
 
 // GetMemAllowedNodeMask returns the bitmask of current process allowed running
@@ -217,25 +256,15 @@ func GetMemAllowedNodeMask() (Bitmask, error) {
 	return mask, nil
 }
 
-// RunOnNodeMask run current process to the given nodes.
+// RunOnNodeMask run current process to the given nodes, the process is
+// allowed to run on the cpus of the given nodes only. The nodes which are not
+// in NodeMask are ignored.
 // @numa_run_on_node_mask_v2
 func RunOnNodeMask(mask Bitmask) error {
-	cpumask := NewBitmask(CPUPossibleCount())
-	m := mask.Clone()
-	for i := 0; i < mask.Len(); i++ {
-		if !m.Get(i) {
-			continue
-		}
-		if !memnodes.Get(i) {
-			continue
-		}
-		cpu, err := NodeToCPUMask(i)
-		if err != nil {
-			return err
-		}
-		for j := 0; j < cpu.Len(); j++ {
-			cpumask.Set(j, true)
-		}
+	t := SystemTopology()
+	cpumask, err := t.nodescpumask(mask)
+	if err != nil {
+		return err
 	}
 	return SetSchedAffinity(0, cpumask)
 }
@@ -4,8 +4,11 @@
 package numa
 
 import (
+	"errors"
 	"fmt"
-	"io/ioutil"
+	"io/fs"
+	"os"
+	"runtime"
 	"strconv"
 	"strings"
 	"syscall"
@@ -15,13 +18,24 @@ import (
 func init() {
 	_, _, e1 := syscall.Syscall6(syscall.SYS_GET_MEMPOLICY, 0, 0, 0, 0, 0, 0)
 	available = e1 != syscall.ENOSYS
-	nnodemax = setupnodemask() // max nodes
-	memnodes = NewBitmask(NodePossibleCount())
-	numanodes = NewBitmask(NodePossibleCount())
-	nconfigurednode = setupconfigurednodes() // configured nodes
-	ncpumax = setupncpu()                    // max cpu
-	nconfiguredcpu = setupnconfiguredcpu()   // configured cpu
-	setupconstraints()
+	t, _ := LoadSystemTopology()
+	topology.Store(t)
+}
+
+// LoadSystemTopology loads a new snapshot of the NUMA topology of current
+// platform from /sys and /proc.
+func LoadSystemTopology() (*Topology, error) {
+	return loadSystemTopology(os.DirFS("/"))
+}
+
+func loadSystemTopology(fsys fs.FS) (*Topology, error) {
+	t, err := loadTopology(fsys, true)
+	if errors.Is(err, fs.ErrNotExist) {
+		// The kernel built without CONFIG_NUMA has no node directory, the
+		// topology without any node is used rather than failing.
+		err = nil
+	}
+	return t, err
 }
 
 // GetMemPolicy retrieves the NUMA policy of the calling process or of a
@@ -124,6 +138,90 @@ func SetMemPolicy(mode int, nodemask Bitmask) (err error) {
 	return
 }
 
+// CheckMemPolicy reports whether the memory policy mode is supported by
+// current kernel, it returns ErrUnsupported if not. The mode may be combined
+// with MPOL_F_STATIC_NODES or MPOL_F_RELATIVE_NODES, modes unknown to this
+// package always report ErrUnsupported. The mode is probed by SetMemPolicy
+// with the nodes allowed by the cpuset on a locked OS thread, then the policy
+// of that thread is restored. If the restoring fails, the thread stays locked
+// so that it is destroyed along with the calling goroutine.
+//
+// The kernel reports an unknown mode and an invalid mode/nodemask combination
+// both with EINVAL, so a supported mode which is rejected with the allowed
+// nodes, e.g. MPOL_F_STATIC_NODES|MPOL_F_RELATIVE_NODES, is reported as
+// ErrUnsupported too.
+func CheckMemPolicy(mode int) error {
+	base := mode &^ MPOL_MODE_FLAGS
+	if base < 0 || base >= MPOL_MAX {
+		return ErrUnsupported
+	}
+	runtime.LockOSThread()
+	locked := false
+	defer func() {
+		if !locked {
+			runtime.UnlockOSThread()
+		}
+	}()
+
+	oldmask := NewBitmask(NodePossibleCount())
+	oldmode, err := GetMemPolicy(oldmask, nil, 0)
+	if err != nil {
+		return err
+	}
+	var nodemask Bitmask
+	if base != MPOL_DEFAULT && base != MPOL_LOCAL {
+		if nodemask, err = GetMemAllowedNodeMask(); err != nil {
+			return err
+		}
+	}
+	err = SetMemPolicy(mode, nodemask)
+	if err == syscall.EINVAL {
+		return ErrUnsupported
+	} else if err != nil {
+		return err
+	}
+	if err = SetMemPolicy(oldmode, oldmask); err != nil {
+		locked = true
+	}
+	return err
+}
+
+const weightedInterleavePath = "/sys/kernel/mm/mempolicy/weighted_interleave"
+
+// WeightedInterleaveWeight returns the weight of the given node, which used
+// by MPOL_WEIGHTED_INTERLEAVE. It returns ErrUnsupported if current kernel
+// does not support MPOL_WEIGHTED_INTERLEAVE.
+func WeightedInterleaveWeight(node int) (int, error) {
+	fname := fmt.Sprintf("%s/node%d", weightedInterleavePath, node)
+	d, err := os.ReadFile(fname)
+	if err != nil {
+		return 0, weightederror(err)
+	}
+	return strconv.Atoi(strings.TrimSpace(string(d)))
+}
+
+// SetWeightedInterleaveWeight sets the weight of the given node, which used
+// by MPOL_WEIGHTED_INTERLEAVE. The weight is in range [1, 255], a node with
+// weight 3 gets 3 pages for each page of a node with weight 1. It requires
+// the root privilege.
+func SetWeightedInterleaveWeight(node, weight int) error {
+	if weight < 1 || weight > 255 {
+		return fmt.Errorf("invalided weight %d", weight)
+	}
+	fname := fmt.Sprintf("%s/node%d", weightedInterleavePath, node)
+	if err := os.WriteFile(fname, []byte(strconv.Itoa(weight)), 0); err != nil {
+		return weightederror(err)
+	}
+	return nil
+}
+
+func weightederror(err error) error {
+	if _, e := os.Stat(weightedInterleavePath); os.IsNotExist(e) {
+		return ErrUnsupported
+	}
+	return err
+}
+
 // MBind sets the NUMA memory policy, which consists of a policy mode and zero
 // or more nodes, for the memory range starting with addr and continuing for
 // length bytes. The memory policy defines from which node memory is allocated.
@@ -171,6 +269,36 @@ func MBind(addr unsafe.Pointer, length, mode, flags int, nodemask Bitmask) (err
 	return
 }
 
+// SetMemPolicyHomeNode sets the home node for the memory range starting with
+// addr and continuing for length bytes, which has been set MPOL_BIND or
+// MPOL_PREFERRED_MANY policy by MBind. The memory is allocated on the home
+// node preferably, and falls back to the nodes of the policy which nearest to
+// the home node. The addr must be aligned to page size.
+// Details to see manpage of set_mempolicy_home_node.
+func SetMemPolicyHomeNode(addr unsafe.Pointer, length int, node int) error {
+	_, _, errno := syscall.Syscall6(_SYS_SET_MEMPOLICY_HOME_NODE, uintptr(addr),
+		uintptr(length), uintptr(node), 0, 0, 0)
+	if errno != 0 {
+		return errno
+	}
+	return nil
+}
+
+// CheckMemPolicyHomeNode reports whether set_mempolicy_home_node is supported
+// by current kernel (since Linux 5.17), it returns ErrUnsupported if not.
+func CheckMemPolicyHomeNode() error {
+	// The non-zero flags is always rejected with EINVAL if it is supported,
+	// the other errors such as EPERM of seccomp are returned as is.
+	_, _, errno := syscall.Syscall6(_SYS_SET_MEMPOLICY_HOME_NODE, 0, 0, 0, 1, 0, 0)
+	switch errno {
+	case 0, syscall.EINVAL:
+		return nil
+	case syscall.ENOSYS:
+		return ErrUnsupported
+	}
+	return errno
+}
+
 // GetSchedAffinity writes the affinity mask of the process whose ID is pid
 // into the input mask. If pid is zero, then the mask of the calling process
 // is returned.
@@ -203,59 +331,21 @@ func SetSchedAffinity(pid int, cpumask Bitmask) error {
 	return nil
 }
 
-/*
- * (do this the way Paul Jackson's libcpuset does it)
- * The nodemask values in /proc/self/status are in an
- * ascii format that uses 9 characters for each 32 bits of mask.
- * (this could also be used to find the cpumask size)
- */
-func setupnodemask() (n int) {
-	d, err := ioutil.ReadFile("/proc/self/status")
-	if err == nil {
-		const stp = "Mems_allowed:\t"
-		for _, line := range strings.Split(string(d), "\n") {
-			if !strings.HasPrefix(line, stp) {
-				continue
-			}
-			n = (len(line) - len(stp) + 1) * 32 / 9
-		}
-	}
-	if n == 0 {
-		n = 16
-		for n < 4096*8 {
-			n <<= 1
-			mask := NewBitmask(n)
-			if _, err := GetMemPolicy(mask, nil, 0); err != nil && err != syscall.EINVAL {
-				break
-			}
-		}
-	}
-	return
-}
-
-func setupconfigurednodes() (n int) {
-	files, err := ioutil.ReadDir("/sys/devices/system/node")
-	if err != nil {
-		return 1
-	}
-	for _, f := range files {
-		if !strings.HasPrefix(f.Name(), "node") {
-			continue
-		}
-		i, _ := strconv.Atoi(f.Name()[4:])
-		if n < i {
-			n = i // maybe some node absence
-		}
-		numanodes.Set(i, true)
-		if _, _, err := NodeMemSize64(i); err == nil {
-			memnodes.Set(i, true)
+// probenodemask detects the max possible node count by get_mempolicy.
+func probenodemask() (n int) {
+	n = 16
+	for n < 4096*8 {
+		n <<= 1
+		mask := NewBitmask(n)
+		if _, err := GetMemPolicy(mask, nil, 0); err != nil && err != syscall.EINVAL {
+			break
 		}
 	}
-	n++
 	return
 }
 
-func setupncpu() (n int) {
+// probencpu detects the max possible cpu count by sched_getaffinity.
+func probencpu() (n int) {
 	length := 4096
 	for {
 		mask := NewBitmask(length)
@@ -270,99 +360,22 @@ func setupncpu() (n int) {
 	}
 }
 
-func setupnconfiguredcpu() (n int) {
-	// sysconf(_SC_NPROCESSORS_CONF)
-	files, err := ioutil.ReadDir("/sys/devices/system/cpu")
-	if err == nil {
-		for _, f := range files {
-			if !f.IsDir() || !strings.HasPrefix(f.Name(), "cpu") {
-				continue
-			}
-			if _, err := strconv.Atoi(f.Name()[3:]); err == nil {
-				n++
-			}
-		}
-		return
-	}
-	// fail back
-	d, _ := ioutil.ReadFile("/proc/cpuinfo")
-	for _, line := range strings.Split(string(d), "\n") {
-		if strings.HasPrefix(line, "processor") {
-			n++
-		}
-	}
-	if n == 0 {
-		n = 1
-	}
-	return
+// NodeMemSize64 return the memory total size and free size of given node.
+func NodeMemSize64(node int) (total int64, free int64, err error) {
+	return SystemTopology().NodeMemSize64(node)
 }
 
-func setupconstraints() {
-	node2cpu = make(map[int]Bitmask)
-	cpu2node = make(map[int]int)
-	for i := 0; i < numanodes.Len(); i++ {
-		if !numanodes.Get(i) {
-			continue
-		}
-		fname := fmt.Sprintf("/sys/devices/system/node/node%d/cpumap", i)
-		d, err := ioutil.ReadFile(fname)
-		if err != nil {
-			continue
-		}
-		nn := 32
-		cpumask := NewBitmask(CPUCount())
-		tokens := strings.Split(strings.TrimSpace(string(d)), ",")
-		for j := 0; j < len(tokens); j++ {
-			mask, _ := strconv.ParseUint(tokens[len(tokens)-1-j], 16, 64)
-			for k := 0; k < nn; k++ {
-				if (mask>>uint64(k))&0x01 != 0 {
-					cpumask.Set(k+j*nn, true)
-				}
-			}
-		}
-		node2cpu[i] = cpumask
-		for j := 0; j < cpumask.Len(); j++ {
-			if cpumask.Get(j) {
-				cpu2node[j] = i
-			}
-		}
-	}
+// NodeMemInfo returns the memory usage of given node.
+func NodeMemInfo(node int) (MemInfo, error) {
+	return SystemTopology().NodeMemInfo(node)
 }
 
-// NodeMemSize64 return the memory total size and free size of given node.
-func NodeMemSize64(node int) (total int64, free int64, err error) {
-	var (
-		d     []byte
-		fname = fmt.Sprintf("/sys/devices/system/node/node%d/meminfo", node)
-	)
-	d, err = ioutil.ReadFile(fname)
-	if err != nil {
-		return
-	}
-	split := func(s, d string) string {
-		return strings.TrimFunc(
-			s[strings.Index(s, d)+len(d):], func(x rune) bool {
-				return x < '0' || x > '9'
-			})
-	}
-	for _, line := range strings.Split(string(d), "\n") {
-		if !strings.HasSuffix(line, "kB") {
-			continue
-		}
-		switch {
-		case strings.Contains(line, "MemTotal"):
-			total, err = strconv.ParseInt(split(line, "MemTotal"), 10, 64)
-			if err != nil {
-				return
-			}
-			total *= 1024
-		case strings.Contains(line, "MemFree"):
-			free, err = strconv.ParseInt(split(line, "MemFree:"), 10, 64)
-			if err != nil {
-				return
-			}
-			free *= 1024
-		}
-	}
-	return
+// NodeStat returns the NUMA allocation counters of given node.
+func NodeStat(node int) (NumaStat, error) {
+	return SystemTopology().NodeStat(node)
+}
+
+// NodeVMStat returns the virtual memory counters of given node.
+func NodeVMStat(node int) (VMStat, error) {
+	return SystemTopology().NodeVMStat(node)
 }
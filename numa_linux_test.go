@@ -4,6 +4,7 @@ import (
 	"fmt"
 	"strings"
 	"testing"
+	"testing/fstest"
 
 	"github.com/stretchr/testify/require"
 )
@@ -34,3 +35,20 @@ func TestCPUAndNodeShow(t *testing.T) {
 		t.Log(fmt.Sprintf("node %d cpus: %s", node, strings.Join(cpu, " ")))
 	}
 }
+
+func TestLoadSystemTopologyWithoutNUMA(t *testing.T) {
+	assert := require.New(t)
+	// The kernel built without CONFIG_NUMA has no sys/devices/system/node.
+	fsys := fstest.MapFS{
+		"proc/self/status":                   {Data: []byte("Name:\tnuma.test\n")},
+		"sys/devices/system/cpu/possible":    {Data: []byte("0-3\n")},
+		"sys/devices/system/cpu/cpu0/online": {Data: []byte("1\n")},
+	}
+	_, err := loadTopology(fsys, false)
+	assert.Error(err)
+	topo, err := loadSystemTopology(fsys)
+	assert.NoError(err)
+	assert.Equal(0, topo.MaxNodeID())
+	assert.Equal(0, topo.NodeCount())
+	assert.True(topo.CPUCount() > 0)
+}
@@ -10,35 +10,41 @@ import (
 
 func init() {
 	// only used for cross-compile
-	nnodemax = 1
-	memnodes = NewBitmask(NodePossibleCount())
-	numanodes = NewBitmask(NodePossibleCount())
-	nconfigurednode = setupconfigurednodes()
-
-	memnodes.Set(0, true)
-	numanodes.Set(0, true)
-
-	ncpumax = runtime.NumCPU()
-	nconfiguredcpu = runtime.NumCPU()
+	t, _ := LoadSystemTopology()
+	topology.Store(t)
+}
 
-	cpu2node = make(map[int]int, ncpumax)
-	for i := 0; i < ncpumax; i++ {
+// LoadSystemTopology loads a new snapshot of the NUMA topology of current
+// platform, which always has a single node on non-linux platform.
+func LoadSystemTopology() (*Topology, error) {
+	ncpu := runtime.NumCPU()
+	cpumask := NewBitmask(ncpu)
+	cpu2node := make(map[int]int, ncpu)
+	for i := 0; i < ncpu; i++ {
+		cpumask.Set(i, true)
 		cpu2node[i] = 0
 	}
-	cpumask := NewBitmask(nconfiguredcpu)
-	for i := 0; i < nconfiguredcpu; i++ {
-		cpumask.Set(i, true)
+	t := &Topology{
+		nnodemax:        1,
+		nconfigurednode: 1,
+		ncpumax:         ncpu,
+		nconfiguredcpu:  ncpu,
+		memnodes:        NewBitmask(1),
+		numanodes:       NewBitmask(1),
+		hasmemory:       NewBitmask(1),
+		cpu2node:        cpu2node,
+		node2cpu:        map[int]Bitmask{0: cpumask},
+		distances:       [][]int{{10}},
 	}
-	node2cpu = map[int]Bitmask{0: cpumask}
+	t.memnodes.Set(0, true)
+	t.numanodes.Set(0, true)
+	t.hasmemory.Set(0, true)
+	return t, nil
 }
 
-func setupconfigurednodes() (n int) {
-	for i := 0; i < NodePossibleCount(); i++ {
-		numanodes.Set(i, true)
-		memnodes.Set(i, true)
-	}
-	return NodePossibleCount()
-}
+func probenodemask() int { return 0 }
+
+func probencpu() int { return 0 }
 
 // GetMemPolicy retrieves the NUMA policy of the calling process or of a
 // memory address, depending on the setting of flags.
@@ -58,6 +64,39 @@ func NodeMemSize64(node int) (total int64, free int64, err error) {
 	return 0, 0, syscall.ENOSYS
 }
 
+// NodeMemInfo returns the memory usage of given node.
+func NodeMemInfo(node int) (MemInfo, error) {
+	return MemInfo{}, syscall.ENOSYS
+}
+
+// NodeStat returns the NUMA allocation counters of given node.
+func NodeStat(node int) (NumaStat, error) {
+	return NumaStat{}, syscall.ENOSYS
+}
+
+// NodeVMStat returns the virtual memory counters of given node.
+func NodeVMStat(node int) (VMStat, error) {
+	return nil, syscall.ENOSYS
+}
+
+// CheckMemPolicy reports whether the memory policy mode is supported by
+// current kernel.
+func CheckMemPolicy(mode int) error {
+	return syscall.ENOSYS
+}
+
+// WeightedInterleaveWeight returns the weight of the given node, which used
+// by MPOL_WEIGHTED_INTERLEAVE.
+func WeightedInterleaveWeight(node int) (int, error) {
+	return 0, syscall.ENOSYS
+}
+
+// SetWeightedInterleaveWeight sets the weight of the given node, which used
+// by MPOL_WEIGHTED_INTERLEAVE.
+func SetWeightedInterleaveWeight(node, weight int) error {
+	return syscall.ENOSYS
+}
+
 // MBind sets the NUMA memory policy, which consists of a policy mode and zero
 // or more nodes, for the memory range starting with addr and continuing for
 // length bytes. The memory policy defines from which node memory is allocated.
@@ -66,6 +105,18 @@ func MBind(addr unsafe.Pointer, length, mode, flags int, nodemask Bitmask) error
 	return syscall.ENOSYS
 }
 
+// SetMemPolicyHomeNode sets the home node for the memory range starting with
+// addr and continuing for length bytes.
+func SetMemPolicyHomeNode(addr unsafe.Pointer, length int, node int) error {
+	return syscall.ENOSYS
+}
+
+// CheckMemPolicyHomeNode reports whether set_mempolicy_home_node is supported
+// by current kernel.
+func CheckMemPolicyHomeNode() error {
+	return syscall.ENOSYS
+}
+
 // GetSchedAffinity writes the affinity mask of the process whose ID is pid
 // into the input mask. If pid is zero, then the mask of the calling process
 // is returned.
@@ -84,7 +135,8 @@ func SetSchedAffinity(pid int, cpumask Bitmask) error {
 func GetCPUAndNode() (cpu int, node int) {
 	cpu = runtime_procPin()
 	runtime_procUnpin()
-	return cpu % ncpumax, nnodemax - 1
+	t := SystemTopology()
+	return cpu % t.ncpumax, t.nnodemax - 1
 }
 
 // Implemented in runtime.
@@ -1,6 +1,7 @@
 package numa
 
 import (
+	"os"
 	"runtime"
 	"sync"
 	"syscall"
@@ -89,16 +90,76 @@ func TestNUMAAPI(t *testing.T) {
 	assert.True(CPUCount() > 0)
 }
 
+func TestDistance(t *testing.T) {
+	var (
+		assert   = require.New(t)
+		nodemask = NodeMask()
+		matrix   = DistanceMatrix()
+	)
+	assert.Len(matrix, MaxNodeID()+1)
+	for i := 0; i < nodemask.Len(); i++ {
+		if !nodemask.Get(i) {
+			continue
+		}
+		d, err := Distance(i, i)
+		assert.NoError(err)
+		assert.Equal(10, d, "node %d", i)
+		for j := 0; j < nodemask.Len(); j++ {
+			if !nodemask.Get(j) {
+				continue
+			}
+			d, err := Distance(i, j)
+			assert.NoError(err)
+			assert.Equal(matrix[i][j], d)
+			assert.True(d >= 10, "distance %d -> %d", i, j)
+		}
+	}
+	_, err := Distance(-1, 0)
+	assert.Error(err)
+	_, err = Distance(0, MaxNodeID()+1)
+	assert.Error(err)
+
+	matrix[0][0] = -1
+	d, err := Distance(0, 0)
+	assert.NoError(err)
+	assert.Equal(10, d)
+}
+
+func TestNodesByDistance(t *testing.T) {
+	var (
+		assert   = require.New(t)
+		nodemask = NodeMask()
+	)
+	for i := 0; i < nodemask.Len(); i++ {
+		if !nodemask.Get(i) {
+			continue
+		}
+		nodes := NodesByDistance(i)
+		assert.Len(nodes, NodeCount())
+		assert.Equal(i, nodes[0])
+		for j := 1; j < len(nodes); j++ {
+			prev, err := Distance(i, nodes[j-1])
+			assert.NoError(err)
+			cur, err := Distance(i, nodes[j])
+			assert.NoError(err)
+			assert.True(prev < cur || prev == cur && nodes[j-1] < nodes[j])
+		}
+	}
+	assert.Nil(NodesByDistance(-1))
+	assert.Nil(NodesByDistance(MaxNodeID() + 1))
+}
+
 func TestMemPolicy(t *testing.T) {
 	if !Available() {
 		t.Skip()
 	}
 	assert := require.New(t)
 
-	t.Log("nnodemask = ", nnodemax)
-	t.Log("nconfigurednode =", nconfigurednode)
-	t.Log("ncpumask =", ncpumax)
-	t.Log("nconfiguredcpu =", nconfiguredcpu)
+	topo := SystemTopology()
+	t.Log("nnodemask = ", topo.nnodemax)
+	t.Log("nconfigurednode =", topo.nconfigurednode)
+	t.Log("ncpumask =", topo.ncpumax)
+	t.Log("nconfiguredcpu =", topo.nconfiguredcpu)
 
 	mode, err := GetMemPolicy(nil, nil, 0)
 	assert.NoError(err)
@@ -106,6 +167,53 @@ func TestMemPolicy(t *testing.T) {
 	assert.NoError(SetMemPolicy(MPOL_DEFAULT, nil))
 }
 
+func TestCheckMemPolicy(t *testing.T) {
+	if !Available() {
+		t.Skip("skip by not available")
+	}
+	assert := require.New(t)
+	mode, err := GetMemPolicy(nil, nil, 0)
+	assert.NoError(err)
+
+	for _, m := range []int{MPOL_DEFAULT, MPOL_PREFERRED, MPOL_BIND, MPOL_INTERLEAVE, MPOL_LOCAL} {
+		assert.NoError(CheckMemPolicy(m), "mode %d", m)
+	}
+	for _, m := range []int{MPOL_PREFERRED_MANY, MPOL_WEIGHTED_INTERLEAVE} {
+		if err := CheckMemPolicy(m); err != nil {
+			assert.Equal(ErrUnsupported, err)
+			t.Log("unsupported mode ", m)
+		}
+	}
+	assert.Equal(ErrUnsupported, CheckMemPolicy(MPOL_MAX))
+	assert.Equal(ErrUnsupported, CheckMemPolicy(-1))
+	assert.Equal(ErrUnsupported, CheckMemPolicy(MPOL_MAX|MPOL_F_STATIC_NODES))
+	if err := CheckMemPolicy(MPOL_BIND | MPOL_F_STATIC_NODES); err != nil {
+		assert.Equal(ErrUnsupported, err)
+	}
+
+	after, err := GetMemPolicy(nil, nil, 0)
+	assert.NoError(err)
+	assert.Equal(mode, after)
+}
+
+func TestWeightedInterleaveWeight(t *testing.T) {
+	if !Available() {
+		t.Skip("skip by not available")
+	}
+	assert := require.New(t)
+	NodeMask().ForEach(func(node int) bool {
+		weight, err := WeightedInterleaveWeight(node)
+		if err == ErrUnsupported {
+			t.Skip("unsupported weighted interleave")
+		}
+		assert.NoError(err)
+		assert.True(weight >= 1 && weight <= 255)
+		return true
+	})
+	assert.Error(SetWeightedInterleaveWeight(0, 0))
+	assert.Error(SetWeightedInterleaveWeight(0, 256))
+}
+
 func TestGetMemAllowedNodeMaskAndBind(t *testing.T) {
 	assert := require.New(t)
 	mask, err := GetMemAllowedNodeMask()
@@ -169,6 +277,31 @@ func TestMBind(t *testing.T) {
 		MBind(unsafe.Pointer(t), 100, MPOL_DEFAULT, 0, nil))
 }
 
+func TestSetMemPolicyHomeNode(t *testing.T) {
+	if !Available() {
+		t.Skip("skip by not available")
+	}
+	if err := CheckMemPolicyHomeNode(); err != nil {
+		t.Skip(err)
+	}
+	var (
+		assert   = require.New(t)
+		nodemask = NodeMask()
+		size     = 4 * os.Getpagesize()
+	)
+	b, err := AllocOnNodePreferred(size, nodemask.First())
+	assert.NoError(err)
+	defer Free(b)
+	addr := unsafe.Pointer(&b[0])
+
+	// home node is only valid for MPOL_BIND and MPOL_PREFERRED_MANY.
+	assert.Equal(syscall.EOPNOTSUPP, SetMemPolicyHomeNode(addr, size, nodemask.First()))
+	assert.NoError(MBind(addr, size, MPOL_BIND, 0, nodemask))
+	assert.NoError(SetMemPolicyHomeNode(addr, size, nodemask.Last()))
+	assert.Equal(syscall.EINVAL, SetMemPolicyHomeNode(addr, size, MaxPossibleNodeID()+1))
+	assert.Equal(syscall.EINVAL, SetMemPolicyHomeNode(unsafe.Pointer(&b[1]), size, nodemask.First()))
+}
+
 func TestGetNodeAndCPU(t *testing.T) {
 	if !Available() {
 		t.Skip("skip by not available")
@@ -0,0 +1,89 @@
+package numa
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// NumaStat is the NUMA allocation counters of a node, which parsed from
+// /sys/devices/system/node/nodeN/numastat. All counters are in pages.
+type NumaStat struct {
+	// NumaHit is the count of memory successfully allocated on this node
+	// as intended.
+	NumaHit uint64
+	// NumaMiss is the count of memory allocated on this node despite the
+	// process preferring some different node.
+	NumaMiss uint64
+	// NumaForeign is the count of memory intended for this node, but
+	// actually allocated on some different node.
+	NumaForeign uint64
+	// InterleaveHit is the count of interleaved memory successfully
+	// allocated on this node as intended.
+	InterleaveHit uint64
+	// LocalNode is the count of memory allocated on this node while a
+	// process was running on it.
+	LocalNode uint64
+	// OtherNode is the count of memory allocated on this node while a
+	// process was running on some other node.
+	OtherNode uint64
+}
+
+// NodeStat returns the NUMA allocation counters of given node.
+func (t *Topology) NodeStat(node int) (stat NumaStat, err error) {
+	fname := fmt.Sprintf("sys/devices/system/node/node%d/numastat", node)
+	d, err := t.readfile(fname)
+	if err != nil {
+		return
+	}
+	for _, line := range strings.Split(string(d), "\n") {
+		tokens := strings.Fields(line)
+		if len(tokens) != 2 {
+			continue
+		}
+		var p *uint64
+		switch tokens[0] {
+		case "numa_hit":
+			p = &stat.NumaHit
+		case "numa_miss":
+			p = &stat.NumaMiss
+		case "numa_foreign":
+			p = &stat.NumaForeign
+		case "interleave_hit":
+			p = &stat.InterleaveHit
+		case "local_node":
+			p = &stat.LocalNode
+		case "other_node":
+			p = &stat.OtherNode
+		default:
+			continue
+		}
+		if *p, err = strconv.ParseUint(tokens[1], 10, 64); err != nil {
+			return NumaStat{}, fmt.Errorf("invalid line %q in %s: %v", line, fname, err)
+		}
+	}
+	return
+}
+
+// Delta returns the increment of the counters from prev to cur, which are
+// sampled from the same node.
+func Delta(prev, cur NumaStat) NumaStat {
+	return NumaStat{
+		NumaHit:       cur.NumaHit - prev.NumaHit,
+		NumaMiss:      cur.NumaMiss - prev.NumaMiss,
+		NumaForeign:   cur.NumaForeign - prev.NumaForeign,
+		InterleaveHit: cur.InterleaveHit - prev.InterleaveHit,
+		LocalNode:     cur.LocalNode - prev.LocalNode,
+		OtherNode:     cur.OtherNode - prev.OtherNode,
+	}
+}
+
+// MissRatio returns the ratio of NumaMiss to all allocations on this node,
+// it returns 0 if there is no allocation.
+func (s NumaStat) MissRatio() float64 {
+	total := s.NumaHit + s.NumaMiss
+	if total == 0 {
+		return 0
+	}
+	return float64(s.NumaMiss) / float64(total)
+}
@@ -0,0 +1,55 @@
+package numa
+
+import (
+	"runtime"
+	"syscall"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestTopologyNodeStat(t *testing.T) {
+	var (
+		assert = require.New(t)
+		topo   = loadTestTopology(t, "dual-socket")
+	)
+	stat, err := topo.NodeStat(1)
+	assert.NoError(err)
+	assert.Equal(NumaStat{
+		NumaHit:       2000000,
+		NumaMiss:      2468,
+		NumaForeign:   1234,
+		InterleaveHit: 34,
+		LocalNode:     1997532,
+		OtherNode:     2468,
+	}, stat)
+
+	_, err = topo.NodeStat(2)
+	assert.Error(err)
+}
+
+func TestNumaStatDelta(t *testing.T) {
+	assert := require.New(t)
+	prev := NumaStat{NumaHit: 100, NumaMiss: 10, NumaForeign: 5, InterleaveHit: 1, LocalNode: 90, OtherNode: 10}
+	cur := NumaStat{NumaHit: 400, NumaMiss: 110, NumaForeign: 5, InterleaveHit: 2, LocalNode: 290, OtherNode: 110}
+	delta := Delta(prev, cur)
+	assert.Equal(NumaStat{NumaHit: 300, NumaMiss: 100, InterleaveHit: 1, LocalNode: 200, OtherNode: 100}, delta)
+	assert.Equal(0.25, delta.MissRatio())
+	assert.Equal(0.0, NumaStat{}.MissRatio())
+}
+
+func TestNodeStat(t *testing.T) {
+	assert := require.New(t)
+	NodeMask().ForEach(func(node int) bool {
+		_, err := NodeStat(node)
+		// The numastat is read from sysfs on linux, which does not depend on
+		// the NUMA syscalls. The counters may be all zero, e.g. on a node
+		// without cpu or which memory just onlined.
+		if runtime.GOOS != "linux" {
+			assert.Equal(syscall.ENOSYS, err, "node %d", node)
+		} else {
+			assert.NoError(err, "node %d", node)
+		}
+		return true
+	})
+}
@@ -0,0 +1,172 @@
+package numa
+
+import (
+	"fmt"
+	"runtime"
+	"strings"
+)
+
+// Policy is a NUMA memory policy, which consists of a mode, optional mode
+// flags and zero or more nodes.
+type Policy struct {
+	// Mode is one of MPOL_DEFAULT, MPOL_PREFERRED, MPOL_BIND, ...
+	Mode int
+	// Flags is the union of MPOL_F_STATIC_NODES and MPOL_F_RELATIVE_NODES.
+	Flags int
+	// Nodes is the nodemask of the policy, it is empty for MPOL_DEFAULT and
+	// MPOL_LOCAL.
+	Nodes Bitmask
+}
+
+var policymodes = []string{
+	MPOL_DEFAULT:             "default",
+	MPOL_PREFERRED:           "preferred",
+	MPOL_BIND:                "bind",
+	MPOL_INTERLEAVE:          "interleave",
+	MPOL_LOCAL:               "local",
+	MPOL_PREFERRED_MANY:      "preferred-many",
+	MPOL_WEIGHTED_INTERLEAVE: "weighted-interleave",
+}
+
+// String returns the text of this policy in the format of
+// "mode[=flags][:nodes]", such as "interleave:0-3", "bind=static:1", "local".
+func (p Policy) String() string {
+	var s string
+	if p.Mode >= 0 && p.Mode < len(policymodes) {
+		s = policymodes[p.Mode]
+	} else {
+		s = fmt.Sprintf("mode(%d)", p.Mode)
+	}
+	switch {
+	case p.Flags&MPOL_F_STATIC_NODES != 0:
+		s += "=static"
+	case p.Flags&MPOL_F_RELATIVE_NODES != 0:
+		s += "=relative"
+	}
+	if !p.Nodes.IsEmpty() {
+		s += ":" + p.Nodes.ListString()
+	}
+	return s
+}
+
+// ParsePolicy parses the policy in the format of "mode[=flags][:nodes]", which
+// likes the numactl options and the mpol option of tmpfs, such as
+// "interleave:0-3", "bind:1", "preferred:0", "local". The preferred without
+// node is the local allocation, which String of CurrentPolicy may return on
+// old kernels. The mode is one of
+// default, preferred, bind, interleave, local, preferred-many and
+// weighted-interleave. The flags is static or relative. The nodes must be
+// less than NodePossibleCount().
+func ParsePolicy(s string) (p Policy, err error) {
+	mode, nodes := s, ""
+	if i := strings.IndexByte(s, ':'); i >= 0 {
+		mode, nodes = s[:i], s[i+1:]
+		if p.Nodes, err = ParseListN(nodes, NodePossibleCount()); err != nil {
+			return Policy{}, fmt.Errorf("invalid policy %q: %v", s, err)
+		}
+		if p.Nodes.IsEmpty() {
+			return Policy{}, fmt.Errorf("invalid policy %q: empty nodes", s)
+		}
+	}
+	if i := strings.IndexByte(mode, '='); i >= 0 {
+		switch mode[i+1:] {
+		case "static":
+			p.Flags = MPOL_F_STATIC_NODES
+		case "relative":
+			p.Flags = MPOL_F_RELATIVE_NODES
+		default:
+			return Policy{}, fmt.Errorf("invalid policy %q: unknown flags", s)
+		}
+		mode = mode[:i]
+	}
+	p.Mode = -1
+	for i, m := range policymodes {
+		if m == mode {
+			p.Mode = i
+		}
+	}
+	switch p.Mode {
+	case -1:
+		return Policy{}, fmt.Errorf("invalid policy %q: unknown mode", s)
+	case MPOL_DEFAULT, MPOL_LOCAL:
+		if nodes != "" || p.Flags != 0 {
+			return Policy{}, fmt.Errorf("invalid policy %q: %s takes no nodes", s, mode)
+		}
+	case MPOL_PREFERRED:
+		// The preferred without node means the local allocation, which is
+		// reported by the kernel before Linux 5.2 rather than MPOL_LOCAL.
+		if nodes != "" && p.Nodes.OnesCount() != 1 {
+			return Policy{}, fmt.Errorf("invalid policy %q: %s takes one node", s, mode)
+		}
+	default:
+		if nodes == "" {
+			return Policy{}, fmt.Errorf("invalid policy %q: %s takes nodes", s, mode)
+		}
+	}
+	return p, nil
+}
+
+// CurrentPolicy returns the memory policy of the calling thread.
+func CurrentPolicy() (Policy, error) {
+	nodes := NewBitmask(NodePossibleCount())
+	mode, err := GetMemPolicy(nodes, nil, 0)
+	if err != nil {
+		return Policy{}, err
+	}
+	return Policy{
+		Mode:  mode &^ MPOL_MODE_FLAGS,
+		Flags: mode & MPOL_MODE_FLAGS,
+		Nodes: nodes,
+	}, nil
+}
+
+// Apply sets this policy as the memory policy of the calling thread, and
+// returns a function which restores the previous policy. Because the policy
+// only affects the calling OS thread, the caller should lock the goroutine to
+// its thread by runtime.LockOSThread until the policy restored.
+func (p Policy) Apply() (restore func() error, err error) {
+	prev, err := CurrentPolicy()
+	if err != nil {
+		return nil, err
+	}
+	if err = SetMemPolicy(p.Mode|p.Flags, p.Nodes); err != nil {
+		return nil, err
+	}
+	return func() error {
+		return SetMemPolicy(prev.Mode|prev.Flags, prev.Nodes)
+	}, nil
+}
+
+// WithPolicy locks the calling goroutine to its OS thread and applies the
+// policy p like Apply, and returns a function which restores the previous
+// policy and unlocks the thread, so it can be used as:
+//
+//	restore, err := numa.WithPolicy(p)
+//	if err != nil {
+//		return err
+//	}
+//	defer restore()
+//
+// The thread is unlocked at once if p failed to apply. If the restore failed,
+// the thread is kept locked, so it is terminated when the goroutine exits
+// rather than running other goroutines with the policy p.
+func WithPolicy(p Policy) (restore func() error, err error) {
+	runtime.LockOSThread()
+	undo, err := p.Apply()
+	if err != nil {
+		runtime.UnlockOSThread()
+		return nil, err
+	}
+	var done bool
+	return func() error {
+		if done {
+			return nil
+		}
+		if err := undo(); err != nil {
+			return err
+		}
+		done = true
+		runtime.UnlockOSThread()
+		return nil
+	}, nil
+}
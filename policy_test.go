@@ -0,0 +1,130 @@
+package numa
+
+import (
+	"fmt"
+	"runtime"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestParsePolicy(t *testing.T) {
+	if NodePossibleCount() < 4 {
+		t.Skip("the possible nodes are less than 4")
+	}
+	assert := require.New(t)
+	var tt = []struct {
+		s     string
+		mode  int
+		flags int
+		nodes string
+	}{
+		{"default", MPOL_DEFAULT, 0, ""},
+		{"local", MPOL_LOCAL, 0, ""},
+		{"preferred:0", MPOL_PREFERRED, 0, "0"},
+		{"preferred", MPOL_PREFERRED, 0, ""},
+		{"bind:1", MPOL_BIND, 0, "1"},
+		{"bind=static:0-1", MPOL_BIND, MPOL_F_STATIC_NODES, "0-1"},
+		{"interleave:0-3", MPOL_INTERLEAVE, 0, "0-3"},
+		{"interleave=relative:0,2", MPOL_INTERLEAVE, MPOL_F_RELATIVE_NODES, "0,2"},
+		{"preferred-many:2-3", MPOL_PREFERRED_MANY, 0, "2-3"},
+		{"weighted-interleave:0-1", MPOL_WEIGHTED_INTERLEAVE, 0, "0-1"},
+	}
+	for _, v := range tt {
+		p, err := ParsePolicy(v.s)
+		assert.NoError(err, v.s)
+		assert.Equal(v.mode, p.Mode, v.s)
+		assert.Equal(v.flags, p.Flags, v.s)
+		assert.Equal(v.nodes, p.Nodes.ListString(), v.s)
+		assert.Equal(v.s, p.String())
+	}
+
+	for _, s := range []string{
+		"", "bind", "bind:", "bind:a", "interleave:3-1", "preferred:0-1",
+		"local:0", "default=static", "bind=foo:0", "foo:0", "Bind:0",
+	} {
+		_, err := ParsePolicy(s)
+		assert.Error(err, s)
+	}
+	_, err := ParsePolicy(fmt.Sprintf("bind:%d", NodePossibleCount()))
+	assert.Error(err)
+	_, err = ParsePolicy("bind:5000")
+	assert.Error(err)
+	assert.Equal("mode(99)", Policy{Mode: 99}.String())
+}
+
+func TestApplyPolicy(t *testing.T) {
+	if !Available() {
+		t.Skip("skip by not available")
+	}
+	runtime.LockOSThread()
+	defer runtime.UnlockOSThread()
+
+	assert := require.New(t)
+	prev, err := CurrentPolicy()
+	assert.NoError(err)
+
+	p := Policy{Mode: MPOL_BIND, Nodes: NodeMask()}
+	restore, err := p.Apply()
+	assert.NoError(err)
+	cur, err := CurrentPolicy()
+	assert.NoError(err)
+	assert.Equal(MPOL_BIND, cur.Mode)
+	assert.True(p.Nodes.Equal(cur.Nodes))
+	assert.NoError(restore())
+	cur, err = CurrentPolicy()
+	assert.NoError(err)
+	assert.Equal(prev, cur)
+
+	restore, err = WithPolicy(Policy{Mode: MPOL_INTERLEAVE, Nodes: NodeMask()})
+	assert.NoError(err)
+	cur, err = CurrentPolicy()
+	assert.NoError(err)
+	assert.Equal(MPOL_INTERLEAVE, cur.Mode)
+	assert.NoError(restore())
+	assert.NoError(restore())
+	cur, err = CurrentPolicy()
+	assert.NoError(err)
+	assert.Equal(prev, cur)
+
+	_, err = Policy{Mode: MPOL_MAX}.Apply()
+	assert.Error(err)
+	_, err = WithPolicy(Policy{Mode: MPOL_MAX})
+	assert.Error(err)
+
+	// The policy of CurrentPolicy round-trips.
+	cur, err = CurrentPolicy()
+	assert.NoError(err)
+	again, err := ParsePolicy(cur.String())
+	assert.NoError(err, cur.String())
+	assert.Equal(cur.String(), again.String())
+}
+
+func TestWithPolicyLocked(t *testing.T) {
+	if !Available() {
+		t.Skip("skip by not available")
+	}
+	var (
+		assert = require.New(t)
+		done   = make(chan error)
+	)
+	// The goroutine is not locked by the caller, WithPolicy locks it, so the
+	// policy is restored on the same thread.
+	go func() {
+		restore, err := WithPolicy(Policy{Mode: MPOL_BIND, Nodes: NodeMask()})
+		if err != nil {
+			done <- err
+			return
+		}
+		tid := Gettid()
+		runtime.Gosched()
+		if tid != Gettid() {
+			err = fmt.Errorf("thread changed from %d to %d", tid, Gettid())
+		}
+		if e := restore(); err == nil {
+			err = e
+		}
+		done <- err
+	}()
+	assert.NoError(<-done)
+}
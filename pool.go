@@ -0,0 +1,151 @@
+package numa
+
+import (
+	"errors"
+	"fmt"
+	"runtime"
+	"sync"
+)
+
+// ErrPoolClosed is returned when submitting to a closed Pool.
+var ErrPoolClosed = errors.New("numa: pool is closed")
+
+// Pool is a pool of worker goroutines which are pinned on NUMA nodes. Each worker
+// is locked to its OS thread, which cpu affinity is the cpus of its node and
+// memory policy is on its node, so the functions run by the worker and the
+// memory they allocate stay on the node.
+type Pool struct {
+	mu      sync.Mutex
+	closed  bool
+	done    chan struct{}  // closed by Close to wake up the blocked Submit
+	sending sync.WaitGroup // the running Submit
+	queues  map[int]chan func()
+	wg      sync.WaitGroup
+}
+
+// NewPool starts n workers on each node which has both memory and cpus, the
+// memory-less nodes are skipped as NodesByDistance. The mode is the memory
+// policy of the workers, MPOL_PREFERRED or MPOL_BIND on the node of the
+// worker.
+func NewPool(n int, mode int) (*Pool, error) {
+	if n <= 0 {
+		return nil, fmt.Errorf("invalided worker count %d", n)
+	}
+	if mode != MPOL_PREFERRED && mode != MPOL_BIND {
+		return nil, fmt.Errorf("invalided memory policy mode %d", mode)
+	}
+	var (
+		p    = &Pool{queues: make(map[int]chan func()), done: make(chan struct{})}
+		errc = make(chan error)
+		nw   int
+		t    = SystemTopology()
+	)
+	t.hasmemory.ForEach(func(node int) bool {
+		cpumask, err := t.NodeToCPUMask(node)
+		if err != nil || cpumask.IsEmpty() {
+			return true
+		}
+		nodemask := NewBitmask(t.NodePossibleCount())
+		nodemask.Set(node, true)
+		queue := make(chan func(), n)
+		p.queues[node] = queue
+		for i := 0; i < n; i++ {
+			p.wg.Add(1)
+			go p.worker(queue, cpumask, Policy{Mode: mode, Nodes: nodemask}, errc)
+			nw++
+		}
+		return true
+	})
+	var err error
+	for i := 0; i < nw; i++ {
+		if e := <-errc; e != nil && err == nil {
+			err = e
+		}
+	}
+	if err == nil && nw == 0 {
+		err = errors.New("numa: no node has cpu")
+	}
+	if err != nil {
+		p.Close()
+		return nil, err
+	}
+	return p, nil
+}
+
+func (p *Pool) worker(queue chan func(), cpumask Bitmask, policy Policy, errc chan<- error) {
+	defer p.wg.Done()
+	// The thread is never unlocked, so it is terminated when the worker
+	// exits rather than running other goroutines with the pinned affinity.
+	runtime.LockOSThread()
+	restore, err := bindthread(cpumask, policy)
+	errc <- err
+	if err != nil {
+		return
+	}
+	defer restore()
+	for fn := range queue {
+		fn()
+	}
+}
+
+// Submit queues fn to run by a worker of the given node. It blocks if the
+// queue of the node is full, and returns ErrPoolClosed if the pool is closed
+// meanwhile. It can be called by the functions running in the pool.
+func (p *Pool) Submit(node int, fn func()) error {
+	queue, ok := p.queues[node]
+	if !ok {
+		return fmt.Errorf("node %d has no worker", node)
+	}
+	p.mu.Lock()
+	if p.closed {
+		p.mu.Unlock()
+		return ErrPoolClosed
+	}
+	p.sending.Add(1)
+	p.mu.Unlock()
+	defer p.sending.Done()
+
+	select {
+	case queue <- fn:
+		return nil
+	case <-p.done:
+		return ErrPoolClosed
+	}
+}
+
+// SubmitLocal queues fn to run by a worker of the node which the caller is
+// running on, which gotten by GetCPUAndNode. If the node has no worker, such
+// as a memory-less node, the nearest node which has worker is used.
+func (p *Pool) SubmitLocal(fn func()) error {
+	_, node := GetCPUAndNode()
+	if _, ok := p.queues[node]; !ok {
+		for _, n := range NodesByDistance(node) {
+			if _, ok := p.queues[n]; ok {
+				node = n
+				break
+			}
+		}
+	}
+	return p.Submit(node, fn)
+}
+
+// Close stops accepting new functions, and waits for the workers to finish
+// the queued functions and exit. The Submit blocked on a full queue returns
+// ErrPoolClosed. It must not be called by the functions running in the pool,
+// which deadlocks.
+func (p *Pool) Close() {
+	p.mu.Lock()
+	closing := !p.closed
+	p.closed = true
+	p.mu.Unlock()
+	if closing {
+		close(p.done)
+		// The queues are closed after no Submit is sending, the workers keep
+		// draining them meanwhile.
+		p.sending.Wait()
+		for _, queue := range p.queues {
+			close(queue)
+		}
+	}
+	p.wg.Wait()
+}
@@ -0,0 +1,145 @@
+package numa
+
+import (
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestPool(t *testing.T) {
+	if !Available() {
+		t.Skip("skip by not available")
+	}
+	assert := require.New(t)
+	for _, mode := range []int{MPOL_PREFERRED, MPOL_BIND} {
+		p, err := NewPool(2, mode)
+		assert.NoError(err)
+
+		var (
+			wg   sync.WaitGroup
+			mu   sync.Mutex
+			errs []error
+			ran  int64
+		)
+		// The functions run on the workers, the failures are collected and
+		// asserted on the test goroutine.
+		check := func(node int, cpumask Bitmask) error {
+			cpu, n := GetCPUAndNode()
+			if n != node || !cpumask.Get(cpu) {
+				return fmt.Errorf("run on cpu %d node %d, want node %d", cpu, n, node)
+			}
+			policy, err := CurrentPolicy()
+			if err != nil {
+				return err
+			}
+			if policy.Mode != mode || !policy.Nodes.Equal(nodesmask(node)) {
+				return fmt.Errorf("policy %v on node %d, want mode %d", policy, node, mode)
+			}
+			return nil
+		}
+		memorynodes().ForEach(func(node int) bool {
+			cpumask, err := NodeToCPUMask(node)
+			assert.NoError(err)
+			for i := 0; i < 10; i++ {
+				wg.Add(1)
+				assert.NoError(p.Submit(node, func() {
+					defer wg.Done()
+					atomic.AddInt64(&ran, 1)
+					if err := check(node, cpumask); err != nil {
+						mu.Lock()
+						errs = append(errs, err)
+						mu.Unlock()
+					}
+				}))
+			}
+			return true
+		})
+		wg.Add(1)
+		assert.NoError(p.SubmitLocal(func() {
+			defer wg.Done()
+			atomic.AddInt64(&ran, 1)
+		}))
+		wg.Wait()
+		assert.Empty(errs)
+		assert.Equal(int64(10*NodeCount()+1), atomic.LoadInt64(&ran))
+
+		assert.Error(p.Submit(-1, func() {}))
+		assert.Error(p.Submit(MaxPossibleNodeID()+1, func() {}))
+
+		p.Close()
+		p.Close()
+		assert.Equal(ErrPoolClosed, p.Submit(memorynodes().First(), func() {}))
+		assert.Equal(ErrPoolClosed, p.SubmitLocal(func() {}))
+	}
+
+	_, err := NewPool(0, MPOL_PREFERRED)
+	assert.Error(err)
+	_, err = NewPool(1, MPOL_INTERLEAVE)
+	assert.Error(err)
+}
+
+func TestPoolCloseDrains(t *testing.T) {
+	if !Available() {
+		t.Skip("skip by not available")
+	}
+	assert := require.New(t)
+	p, err := NewPool(1, MPOL_PREFERRED)
+	assert.NoError(err)
+	var ran int64
+	node := memorynodes().First()
+	for i := 0; i < 100; i++ {
+		assert.NoError(p.Submit(node, func() { atomic.AddInt64(&ran, 1) }))
+	}
+	p.Close()
+	assert.Equal(int64(100), atomic.LoadInt64(&ran))
+}
+
+func TestPoolCloseReentrantSubmit(t *testing.T) {
+	if !Available() {
+		t.Skip("skip by not available")
+	}
+	var (
+		assert  = require.New(t)
+		node    = memorynodes().First()
+		started = make(chan struct{})
+		result  = make(chan error, 1)
+		closed  = make(chan struct{})
+	)
+	p, err := NewPool(1, MPOL_PREFERRED)
+	assert.NoError(err)
+	// The function submits to its own full queue until the pool closed, the
+	// Close must not deadlock with it.
+	assert.NoError(p.Submit(node, func() {
+		close(started)
+		for {
+			if err := p.Submit(node, func() {}); err != nil {
+				result <- err
+				return
+			}
+		}
+	}))
+	<-started
+	go func() {
+		p.Close()
+		close(closed)
+	}()
+	select {
+	case <-closed:
+	case <-time.After(10 * time.Second):
+		t.Fatal("Close deadlocked with the reentrant Submit")
+	}
+	assert.Equal(ErrPoolClosed, <-result)
+}
+
+// nodesmask returns the nodemask of the given nodes.
+func nodesmask(nodes ...int) Bitmask {
+	mask := NewBitmask(NodePossibleCount())
+	for _, node := range nodes {
+		mask.Set(node, true)
+	}
+	return mask
+}
@@ -0,0 +1,77 @@
+package numa
+
+import "time"
+
+// RunProcessOnNode sets all threads of current process run on given node,
+// unlike RunOnNode which only affects the calling thread. The special node -1
+// will set current process on all available nodes. The report is same with
+// SetProcessAffinity.
+func RunProcessOnNode(node int) (report map[int]error, err error) {
+	cpumask, err := nodecpumask(node)
+	if err != nil {
+		return nil, err
+	}
+	return SetProcessAffinity(cpumask)
+}
+
+// RunProcessOnNodeMask sets all threads of current process run on the given
+// nodes, unlike RunOnNodeMask which only affects the calling thread. The
+// report is same with SetProcessAffinity.
+//
+// There is no process-wide variant of Bind, because set_mempolicy(2) only
+// sets the memory policy of the calling thread, the policy of other threads
+// cannot be changed. Start the process by "numactl --membind" instead, so
+// all threads inherit the policy.
+func RunProcessOnNodeMask(mask Bitmask) (report map[int]error, err error) {
+	cpumask, err := SystemTopology().nodescpumask(mask)
+	if err != nil {
+		return nil, err
+	}
+	return SetProcessAffinity(cpumask)
+}
+
+// KeepProcessAffinity applies cpumask to all threads of current process by
+// SetProcessAffinity immediately and every interval, so the threads which
+// created by the runtime later are kept consistent. If the interval is not
+// positive, the cpumask is applied once only. If fn is not nil, it is called
+// with the result of each SetProcessAffinity. The returned function stops the
+// keeping. The threads pinned by Do or the workers of Pool are skipped like
+// SetProcessAffinity.
+func KeepProcessAffinity(cpumask Bitmask, interval time.Duration, fn func(report map[int]error, err error)) (stop func()) {
+	var (
+		done = make(chan struct{})
+		exit = make(chan struct{})
+	)
+	cpumask = cpumask.Clone()
+	apply := func() {
+		report, err := SetProcessAffinity(cpumask)
+		if fn != nil {
+			fn(report, err)
+		}
+	}
+	apply()
+	if interval <= 0 {
+		return func() {}
+	}
+	go func() {
+		defer close(exit)
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-done:
+				return
+			case <-ticker.C:
+				apply()
+			}
+		}
+	}()
+	return func() {
+		select {
+		case <-done:
+		default:
+			close(done)
+		}
+		<-exit
+	}
+}
@@ -0,0 +1,70 @@
+//go:build linux
+// +build linux
+
+package numa
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+	"syscall"
+)
+
+// SetProcessAffinity sets the cpu affinity of every thread of current process
+// to cpumask, by enumerating /proc/self/task. SetSchedAffinity(0, ...) only
+// affects the calling thread, but the Go runtime runs goroutines on many
+// threads. The report contains the result of each thread keyed by the thread
+// id, nil means success. The threads which exited during the call are not in
+// the report. The err is not nil if the threads failed to enumerate or any
+// thread failed to set. The threads pinned by Do or the workers of Pool are
+// skipped and not in the report, so they keep the cpus of their node.
+//
+// The threads which created later inherit the affinity of their creator,
+// use KeepProcessAffinity to keep them consistent.
+func SetProcessAffinity(cpumask Bitmask) (report map[int]error, err error) {
+	tids, err := tasks(0)
+	if err != nil {
+		return nil, err
+	}
+	var failed int
+	report = make(map[int]error, len(tids))
+	for _, tid := range tids {
+		skipped, e := setunpinned(tid, cpumask)
+		if skipped || e == syscall.ESRCH {
+			continue // the thread is pinned or has exited
+		}
+		if e != nil {
+			failed++
+		}
+		report[tid] = e
+	}
+	if failed != 0 {
+		err = fmt.Errorf("%d of %d threads failed to set affinity", failed, len(report))
+	}
+	return report, err
+}
+
+// tasks returns the thread ids of the process pid, zero means current
+// process.
+func tasks(pid int) ([]int, error) {
+	dir := "/proc/self/task"
+	if pid != 0 {
+		dir = fmt.Sprintf("/proc/%d/task", pid)
+	}
+	f, err := os.Open(dir)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	names, err := f.Readdirnames(-1)
+	if err != nil {
+		return nil, err
+	}
+	tids := make([]int, 0, len(names))
+	for _, name := range names {
+		if tid, err := strconv.Atoi(name); err == nil {
+			tids = append(tids, tid)
+		}
+	}
+	return tids, nil
+}
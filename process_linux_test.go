@@ -0,0 +1,239 @@
+package numa
+
+import (
+	"runtime"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestSetProcessAffinity(t *testing.T) {
+	if !Available() {
+		t.Skip("skip by not available")
+	}
+	assert := require.New(t)
+	defer saveProcessAffinity(t)()
+
+	tids, err := tasks(0)
+	assert.NoError(err)
+	assert.True(len(tids) > 0)
+
+	threadmask := func(tid int) Bitmask {
+		mask := NewBitmask(CPUPossibleCount())
+		_, err := GetSchedAffinity(tid, mask)
+		assert.NoError(err)
+		return mask
+	}
+
+	NodeMask().ForEach(func(node int) bool {
+		cpumask, err := NodeToCPUMask(node)
+		assert.NoError(err)
+		report, err := RunProcessOnNode(node)
+		assert.NoError(err)
+		assert.NotEmpty(report)
+		for tid, err := range report {
+			assert.NoError(err, "tid %d", tid)
+			assert.True(cpumask.Equal(threadmask(tid)), "tid %d", tid)
+		}
+		return true
+	})
+
+	_, err = RunProcessOnNode(-2)
+	assert.Error(err)
+	report, err := SetProcessAffinity(NewBitmask(CPUPossibleCount()))
+	assert.Error(err)
+	for _, err := range report {
+		assert.Error(err)
+	}
+}
+
+func TestKeepProcessAffinity(t *testing.T) {
+	if !Available() {
+		t.Skip("skip by not available")
+	}
+	var (
+		assert  = require.New(t)
+		mu      sync.Mutex
+		applied int
+		errs    []error
+		allmask = NewBitmask(CPUPossibleCount())
+	)
+	allmask.SetAll()
+	defer saveProcessAffinity(t)()
+
+	cpumask, err := NodeToCPUMask(NodeMask().First())
+	assert.NoError(err)
+	// The callback runs on the keeping goroutine, the errors are asserted on
+	// the test goroutine.
+	stop := KeepProcessAffinity(cpumask, 10*time.Millisecond, func(report map[int]error, err error) {
+		mu.Lock()
+		applied++
+		if err != nil {
+			errs = append(errs, err)
+		}
+		mu.Unlock()
+	})
+
+	// a new thread created by a locked goroutine with a different affinity
+	var (
+		ready = make(chan error)
+		done  = make(chan struct{})
+	)
+	go func() {
+		runtime.LockOSThread()
+		defer runtime.UnlockOSThread()
+		ready <- SetSchedAffinity(0, allmask)
+		<-done
+	}()
+	assert.NoError(<-ready)
+	time.Sleep(100 * time.Millisecond)
+	stop()
+	stop()
+	close(done)
+
+	mu.Lock()
+	assert.True(applied > 1)
+	assert.Empty(errs)
+	mu.Unlock()
+	tids, err := tasks(0)
+	assert.NoError(err)
+	for _, tid := range tids {
+		mask := NewBitmask(CPUPossibleCount())
+		if _, err := GetSchedAffinity(tid, mask); err == nil {
+			assert.True(cpumask.Equal(mask), "tid %d", tid)
+		}
+	}
+}
+
+// saveProcessAffinity saves the affinity of all threads of current process,
+// and returns a function which restores them. The threads created later get
+// the affinity of the calling thread.
+func saveProcessAffinity(t *testing.T) (restore func()) {
+	tids, err := tasks(0)
+	require.NoError(t, err)
+	var (
+		saved = make(map[int]Bitmask, len(tids))
+		self  = NewBitmask(CPUPossibleCount())
+	)
+	_, err = GetSchedAffinity(0, self)
+	require.NoError(t, err)
+	for _, tid := range tids {
+		mask := NewBitmask(CPUPossibleCount())
+		if _, err := GetSchedAffinity(tid, mask); err == nil {
+			saved[tid] = mask
+		}
+	}
+	return func() {
+		tids, _ := tasks(0)
+		for _, tid := range tids {
+			mask, ok := saved[tid]
+			if !ok {
+				mask = self
+			}
+			SetSchedAffinity(tid, mask)
+		}
+	}
+}
+
+func TestRunProcessOnNodeMask(t *testing.T) {
+	if !Available() {
+		t.Skip("skip by not available")
+	}
+	assert := require.New(t)
+	defer saveProcessAffinity(t)()
+
+	var (
+		node     = NodeMask().First()
+		nodemask = NewBitmask(NodePossibleCount())
+	)
+	nodemask.Set(node, true)
+	cpumask, err := NodeToCPUMask(node)
+	assert.NoError(err)
+	report, err := RunProcessOnNodeMask(nodemask)
+	assert.NoError(err)
+	assert.NotEmpty(report)
+	for tid := range report {
+		mask := NewBitmask(CPUPossibleCount())
+		_, err := GetSchedAffinity(tid, mask)
+		assert.NoError(err, "tid %d", tid)
+		assert.True(cpumask.Equal(mask), "tid %d", tid)
+	}
+
+	// The memory-less or absent nodes have no cpu.
+	_, err = RunProcessOnNodeMask(NewBitmask(NodePossibleCount()))
+	assert.Error(err)
+}
+
+func TestProcessAffinitySkipPinned(t *testing.T) {
+	if !Available() {
+		t.Skip("skip by not available")
+	}
+	assert := require.New(t)
+	defer saveProcessAffinity(t)()
+
+	var (
+		node     = NodeMask().First()
+		tids     = make(chan int)
+		done     = make(chan struct{})
+		exit     = make(chan error)
+		applied  int
+		reported bool
+	)
+	go func() {
+		exit <- Do(node, func() {
+			tids <- Gettid()
+			<-done
+		})
+	}()
+	tid := <-tids
+	assert.True(ispinned(tid))
+
+	// The zero interval applies once only. The empty cpumask is rejected by
+	// every thread, so the affinity is unchanged.
+	stop := KeepProcessAffinity(NewBitmask(CPUPossibleCount()), 0, func(report map[int]error, err error) {
+		applied++
+		_, reported = report[tid]
+	})
+	stop()
+	close(done)
+	assert.NoError(<-exit)
+	assert.Equal(1, applied)
+	assert.False(reported, "the pinned thread is not skipped")
+	assert.False(ispinned(tid))
+}
+
+func TestProcessAffinityWhilePoolStarts(t *testing.T) {
+	if !Available() {
+		t.Skip("skip by not available")
+	}
+	assert := require.New(t)
+	defer saveProcessAffinity(t)()
+
+	allowed, err := RunningCPUMask()
+	assert.NoError(err)
+	cpumask := NewBitmask(CPUPossibleCount())
+	cpumask.Set(allowed.First(), true)
+
+	for i := 0; i < 10; i++ {
+		stop := KeepProcessAffinity(cpumask, time.Microsecond, nil)
+		p, err := NewPool(1, MPOL_PREFERRED)
+		stop()
+		assert.NoError(err)
+
+		memorynodes().ForEach(func(node int) bool {
+			want, err := NodeToCPUMask(node)
+			assert.NoError(err)
+			got := make(chan Bitmask, 1)
+			assert.NoError(p.Submit(node, func() {
+				mask, _ := RunningCPUMask()
+				got <- mask
+			}))
+			mask := <-got
+			assert.True(want.Equal(mask), "node %d got %s", node, mask.ListString())
+			return true
+		})
+		p.Close()
+	}
+}
@@ -0,0 +1,12 @@
+//go:build !linux
+// +build !linux
+
+package numa
+
+import "syscall"
+
+// SetProcessAffinity sets the cpu affinity of every thread of current process
+// to cpumask.
+func SetProcessAffinity(cpumask Bitmask) (report map[int]error, err error) {
+	return nil, syscall.ENOSYS
+}
@@ -0,0 +1,73 @@
+package numa
+
+import (
+	"fmt"
+	"math"
+	"reflect"
+	"unsafe"
+)
+
+// NodeSlice is a slice which backed by the memory out of the Go heap and
+// allocated on a given node. It must be released by Close.
+type NodeSlice[T any] struct {
+	b []byte
+	s []T
+}
+
+// MakeSlice returns a NodeSlice of n elements, which memory is allocated by
+// AllocOnNode. The memory policy is MPOL_BIND, so the pages never land on
+// other nodes, the node must have memory. Because the garbage collector
+// cannot see the memory, the T which contains any pointer is refused.
+func MakeSlice[T any](n int, node int) (*NodeSlice[T], error) {
+	var zero T
+	typ := reflect.TypeOf(&zero).Elem()
+	if haspointer(typ) {
+		return nil, fmt.Errorf("type %v contains pointer", typ)
+	}
+	size := int(typ.Size())
+	if n <= 0 || size == 0 || n > math.MaxInt/size {
+		return nil, fmt.Errorf("invalided slice size %d of type %v", n, typ)
+	}
+	b, err := AllocOnNode(n*size, node)
+	if err != nil {
+		return nil, err
+	}
+	return &NodeSlice[T]{
+		b: b,
+		s: unsafe.Slice((*T)(unsafe.Pointer(&b[0])), n),
+	}, nil
+}
+
+// Slice returns the underlying slice, which must not be used after Close.
+func (s *NodeSlice[T]) Slice() []T {
+	return s.s
+}
+
+// Close releases the memory of this slice.
+func (s *NodeSlice[T]) Close() error {
+	if s.b == nil {
+		return nil
+	}
+	b := s.b
+	s.b, s.s = nil, nil
+	return Free(b)
+}
+
+// haspointer reports whether the value of type t contains any pointer.
+func haspointer(t reflect.Type) bool {
+	switch t.Kind() {
+	case reflect.Array:
+		return t.Len() != 0 && haspointer(t.Elem())
+	case reflect.Struct:
+		for i := 0; i < t.NumField(); i++ {
+			if haspointer(t.Field(i).Type) {
+				return true
+			}
+		}
+		return false
+	case reflect.Ptr, reflect.UnsafePointer, reflect.Map, reflect.Slice,
+		reflect.String, reflect.Interface, reflect.Chan, reflect.Func:
+		return true
+	}
+	return false
+}
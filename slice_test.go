@@ -0,0 +1,82 @@
+package numa
+
+import (
+	"math"
+	"reflect"
+	"testing"
+	"unsafe"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestMakeSlice(t *testing.T) {
+	if !Available() {
+		t.Skip("skip by not available")
+	}
+	type entry struct {
+		Key   uint64
+		Value [4]int32
+	}
+	assert := require.New(t)
+	memorynodes().ForEach(func(node int) bool {
+		s, err := MakeSlice[entry](1000, node)
+		assert.NoError(err)
+		slice := s.Slice()
+		assert.Len(slice, 1000)
+		for i := range slice {
+			slice[i].Key = uint64(i)
+		}
+		assert.Equal(uint64(999), slice[999].Key)
+		n, err := GetMemPolicy(nil, unsafe.Pointer(&slice[0]), MPOL_F_NODE|MPOL_F_ADDR)
+		assert.NoError(err)
+		assert.Equal(node, n)
+		assert.NoError(s.Close())
+		assert.Nil(s.Slice())
+		assert.NoError(s.Close())
+		return true
+	})
+
+	_, err := MakeSlice[*int](10, 0)
+	assert.Error(err)
+	_, err = MakeSlice[struct {
+		A int
+		B [2]string
+	}](10, 0)
+	assert.Error(err)
+	_, err = MakeSlice[[]byte](10, 0)
+	assert.Error(err)
+	_, err = MakeSlice[int](0, 0)
+	assert.Error(err)
+	_, err = MakeSlice[struct{}](10, 0)
+	assert.Error(err)
+	_, err = MakeSlice[int](10, -1)
+	assert.Error(err)
+	// n*size overflows int.
+	_, err = MakeSlice[[4096]byte](math.MaxInt/4096+1, 0)
+	assert.Error(err)
+	_, err = MakeSlice[int64](math.MaxInt, 0)
+	assert.Error(err)
+}
+
+func TestHasPointer(t *testing.T) {
+	assert := require.New(t)
+	var tt = []struct {
+		v   interface{}
+		has bool
+	}{
+		{int64(0), false},
+		{[8]float64{}, false},
+		{struct{ A, B int }{}, false},
+		{[0]*int{}, false},
+		{"", true},
+		{&struct{}{}, true},
+		{[]int{}, true},
+		{map[int]int{}, true},
+		{struct{ A [2]interface{} }{}, true},
+		{unsafe.Pointer(nil), true},
+		{func() {}, true},
+	}
+	for _, v := range tt {
+		assert.Equal(v.has, haspointer(reflect.TypeOf(v.v)), "%T", v.v)
+	}
+}
@@ -0,0 +1,8 @@
+//go:build linux && !mips && !mipsle && !mips64 && !mips64le
+// +build linux,!mips,!mipsle,!mips64,!mips64le
+
+package numa
+
+// The syscall number of set_mempolicy_home_node, which is same on all
+// architectures of the unified syscall table except mips.
+const _SYS_SET_MEMPOLICY_HOME_NODE = 450
@@ -0,0 +1,5 @@
+package numa
+
+// The syscall number of migrate_pages, which is absent in the syscall
+// package of arm.
+const _SYS_MIGRATE_PAGES = 400
@@ -0,0 +1,8 @@
+//go:build linux && (mips64 || mips64le)
+// +build linux
+// +build mips64 mips64le
+
+package numa
+
+// The syscall number of set_mempolicy_home_node of mips n64 ABI.
+const _SYS_SET_MEMPOLICY_HOME_NODE = 5450
@@ -0,0 +1,8 @@
+//go:build linux && (mips || mipsle)
+// +build linux
+// +build mips mipsle
+
+package numa
+
+// The syscall number of set_mempolicy_home_node of mips o32 ABI.
+const _SYS_SET_MEMPOLICY_HOME_NODE = 4450
@@ -0,0 +1,8 @@
+//go:build linux && !arm
+// +build linux,!arm
+
+package numa
+
+import "syscall"
+
+const _SYS_MIGRATE_PAGES = syscall.SYS_MIGRATE_PAGES
@@ -0,0 +1,143 @@
+package numa
+
+import (
+	"runtime"
+	"sync"
+)
+
+// pinned is the set of thread ids which pinned by Do and the workers of Pool,
+// which are skipped by SetProcessAffinity. The pinmu is held while a thread
+// changes its affinity along with pinned, so SetProcessAffinity never
+// overrides the affinity of a pinned thread.
+var (
+	pinmu  sync.Mutex
+	pinned = make(map[int]struct{})
+)
+
+// ispinned reports whether the thread is pinned by Do or Pool.
+func ispinned(tid int) bool {
+	pinmu.Lock()
+	defer pinmu.Unlock()
+	_, ok := pinned[tid]
+	return ok
+}
+
+// setunpinned sets the cpu affinity of the thread tid to cpumask unless it is
+// pinned by Do or Pool.
+func setunpinned(tid int, cpumask Bitmask) (skipped bool, err error) {
+	pinmu.Lock()
+	defer pinmu.Unlock()
+	if _, ok := pinned[tid]; ok {
+		return true, nil
+	}
+	return false, SetSchedAffinity(tid, cpumask)
+}
+
+// pin marks the calling thread tid as pinned and sets its cpu affinity, the
+// previous affinity is saved into prevmask.
+func pin(tid int, cpumask, prevmask Bitmask) error {
+	pinmu.Lock()
+	defer pinmu.Unlock()
+	if _, err := GetSchedAffinity(0, prevmask); err != nil {
+		return err
+	}
+	pinned[tid] = struct{}{}
+	err := SetSchedAffinity(0, cpumask)
+	if err != nil {
+		delete(pinned, tid)
+	}
+	return err
+}
+
+// unpin unmarks the calling thread tid and restores its cpu affinity.
+func unpin(tid int, prevmask Bitmask) error {
+	pinmu.Lock()
+	defer pinmu.Unlock()
+	delete(pinned, tid)
+	return SetSchedAffinity(0, prevmask)
+}
+
+// ThreadInfo is the placement of a thread.
+type ThreadInfo struct {
+	// TID is the thread id.
+	TID int
+	// CPU is the cpu which the thread last ran on.
+	CPU int
+	// Node is the node of CPU, it is -1 if unknown.
+	Node int
+	// Allowed is the cpus which the thread is allowed to run on.
+	Allowed Bitmask
+}
+
+// Do runs fn on the given node. It locks the calling goroutine to its OS
+// thread, sets the cpu affinity of the thread to the cpus of the node and the
+// memory policy of the thread to MPOL_PREFERRED the node, then runs fn. After
+// fn returned, the previous affinity and memory policy of the thread are
+// restored and the thread is unlocked. If the thread failed to restore, it is
+// kept locked, so it will be terminated when the goroutine exits rather than
+// running other goroutines with the wrong affinity.
+func Do(node int, fn func()) (err error) {
+	cpumask, err := NodeToCPUMask(node)
+	if err != nil {
+		return err
+	}
+	nodemask := NewBitmask(NodePossibleCount())
+	nodemask.Set(node, true)
+
+	runtime.LockOSThread()
+	restore, err := bindthread(cpumask, Policy{Mode: MPOL_PREFERRED, Nodes: nodemask})
+	if err != nil {
+		if _, ok := err.(*restoreerror); !ok {
+			runtime.UnlockOSThread()
+		}
+		return err
+	}
+	defer func() {
+		if err = restore(); err == nil {
+			runtime.UnlockOSThread()
+		}
+	}()
+	fn()
+	return nil
+}
+
+// bindthread sets the cpu affinity and memory policy of the calling thread,
+// and returns a function which restores the previous ones. The calling
+// goroutine must be locked to its thread. The thread is marked as pinned until
+// restored, so SetProcessAffinity does not override its affinity. If it fails
+// and the previous affinity can not be restored, the error is a *restoreerror
+// and the thread must be kept locked.
+func bindthread(cpumask Bitmask, p Policy) (restore func() error, err error) {
+	tid, prevmask := Gettid(), NewBitmask(CPUPossibleCount())
+	if err = pin(tid, cpumask, prevmask); err != nil {
+		return nil, err
+	}
+	restorepolicy, err := p.Apply()
+	if err != nil {
+		if e := unpin(tid, prevmask); e != nil {
+			return nil, &restoreerror{err: e}
+		}
+		return nil, err
+	}
+	return func() error {
+		err := restorepolicy()
+		if e := unpin(tid, prevmask); err == nil {
+			err = e
+		}
+		return err
+	}, nil
+}
+
+// restoreerror is returned by bindthread if the thread failed to restore its
+// previous affinity, so the thread is unusable for other goroutines.
+type restoreerror struct {
+	err error
+}
+
+func (e *restoreerror) Error() string {
+	return "numa: failed to restore the thread affinity: " + e.err.Error()
+}
+
+func (e *restoreerror) Unwrap() error {
+	return e.err
+}
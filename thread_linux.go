@@ -0,0 +1,110 @@
+//go:build linux
+// +build linux
+
+package numa
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"strconv"
+	"strings"
+	"syscall"
+)
+
+// Gettid returns the thread id of the calling thread. The goroutine should be
+// locked to its thread by runtime.LockOSThread, otherwise it may run on
+// another thread at once.
+func Gettid() int {
+	return syscall.Gettid()
+}
+
+// ThreadAffinity returns the cpu affinity mask of the thread tid.
+func ThreadAffinity(tid int) (Bitmask, error) {
+	cpumask := NewBitmask(CPUPossibleCount())
+	if _, err := GetSchedAffinity(tid, cpumask); err != nil {
+		return nil, err
+	}
+	return cpumask, nil
+}
+
+// SetThreadAffinity sets the cpu affinity mask of the thread tid.
+func SetThreadAffinity(tid int, cpumask Bitmask) error {
+	return SetSchedAffinity(tid, cpumask)
+}
+
+// Threads returns the placement of every thread of the process pid, zero
+// means current process. The threads which exited during the call are
+// skipped.
+func Threads(pid int) ([]ThreadInfo, error) {
+	tids, err := tasks(pid)
+	if err != nil {
+		return nil, err
+	}
+	dir := "/proc/self/task"
+	if pid != 0 {
+		dir = fmt.Sprintf("/proc/%d/task", pid)
+	}
+	threads := make([]ThreadInfo, 0, len(tids))
+	for _, tid := range tids {
+		info, err := readthread(fmt.Sprintf("%s/%d", dir, tid))
+		if threadexited(err) {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		info.TID = tid
+		threads = append(threads, info)
+	}
+	return threads, nil
+}
+
+// threadexited reports whether err is caused by the thread exited during
+// reading its files, the *os.PathError is unwrapped.
+func threadexited(err error) bool {
+	return errors.Is(err, fs.ErrNotExist) || errors.Is(err, syscall.ESRCH)
+}
+
+func readthread(dir string) (info ThreadInfo, err error) {
+	d, err := os.ReadFile(dir + "/stat")
+	if err != nil {
+		return
+	}
+	if info.CPU, err = parsestatcpu(string(d)); err != nil {
+		return
+	}
+	if info.Node, err = CPUToNode(info.CPU); err != nil {
+		info.Node, err = -1, nil
+	}
+	d, err = os.ReadFile(dir + "/status")
+	if err != nil {
+		return
+	}
+	const stp = "Cpus_allowed_list:"
+	for _, line := range strings.Split(string(d), "\n") {
+		if strings.HasPrefix(line, stp) {
+			info.Allowed, err = ParseList(line[len(stp):])
+			return
+		}
+	}
+	err = fmt.Errorf("%s/status: Cpus_allowed_list not found", dir)
+	return
+}
+
+// parsestatcpu returns the processor field (the 39th) of /proc/pid/stat. The
+// comm field is enclosed in parentheses and may contain any character, so
+// the fields are counted after the last ')'.
+func parsestatcpu(stat string) (int, error) {
+	i := strings.LastIndexByte(stat, ')')
+	if i < 0 {
+		return 0, fmt.Errorf("invalid stat %q", stat)
+	}
+	fields := strings.Fields(stat[i+1:])
+	const processor = 39 - 3 // the fields after comm start from the 3rd
+	if len(fields) <= processor {
+		return 0, fmt.Errorf("invalid stat %q", stat)
+	}
+	return strconv.Atoi(fields[processor])
+}
@@ -0,0 +1,81 @@
+package numa
+
+import (
+	"os"
+	"runtime"
+	"syscall"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseStatCPU(t *testing.T) {
+	assert := require.New(t)
+	const stat = "4242 (my (weird) comm) S 1 4242 4242 0 -1 4194560 1033 0 0 0 1 2 0 0 20 0 7 0 " +
+		"123456 1234567 890 18446744073709551615 1 1 0 0 0 0 0 0 0 0 0 0 17 5 0 0 0 0 0 0 0 0 0 0 0 0 0\n"
+	cpu, err := parsestatcpu(stat)
+	assert.NoError(err)
+	assert.Equal(5, cpu)
+
+	_, err = parsestatcpu("4242 (comm S 1 2 3")
+	assert.Error(err)
+	_, err = parsestatcpu("4242 (comm) S 1 2 3")
+	assert.Error(err)
+}
+
+func TestThreadExited(t *testing.T) {
+	assert := require.New(t)
+	_, err := os.ReadFile("/proc/self/task/-1/stat")
+	assert.True(threadexited(err))
+	// The read fails with ESRCH if the thread exited after open.
+	assert.True(threadexited(&os.PathError{Op: "read", Path: "stat", Err: syscall.ESRCH}))
+	assert.False(threadexited(&os.PathError{Op: "read", Path: "stat", Err: syscall.EACCES}))
+	assert.False(threadexited(nil))
+}
+
+func TestThreads(t *testing.T) {
+	if !Available() {
+		t.Skip("skip by not available")
+	}
+	runtime.LockOSThread()
+	defer runtime.UnlockOSThread()
+
+	assert := require.New(t)
+	tid := Gettid()
+	assert.True(tid > 0)
+
+	prev, err := ThreadAffinity(tid)
+	assert.NoError(err)
+	defer SetThreadAffinity(tid, prev)
+
+	node := NodeMask().Last()
+	cpumask, err := NodeToCPUMask(node)
+	assert.NoError(err)
+	assert.NoError(SetThreadAffinity(tid, cpumask))
+	got, err := ThreadAffinity(tid)
+	assert.NoError(err)
+	assert.True(cpumask.Equal(got))
+
+	for _, pid := range []int{0, os.Getpid()} {
+		threads, err := Threads(pid)
+		assert.NoError(err)
+		assert.True(len(threads) > 0)
+		var found bool
+		for _, th := range threads {
+			assert.True(th.CPU >= 0 && th.CPU < CPUPossibleCount())
+			assert.False(th.Allowed.IsEmpty())
+			if th.TID == tid {
+				found = true
+				assert.True(cpumask.Equal(th.Allowed), th.Allowed.ListString())
+				assert.True(cpumask.Get(th.CPU))
+				assert.Equal(node, th.Node)
+			}
+		}
+		assert.True(found)
+	}
+
+	_, err = Threads(-1)
+	assert.Error(err)
+	_, err = ThreadAffinity(-1)
+	assert.Error(err)
+}
@@ -0,0 +1,28 @@
+//go:build !linux
+// +build !linux
+
+package numa
+
+import "syscall"
+
+// Gettid returns -1, which is never a valid thread id, because the thread id
+// is unsupported on non-linux platform. Note that zero means the calling
+// thread to ThreadAffinity and SetThreadAffinity.
+func Gettid() int {
+	return -1
+}
+
+// ThreadAffinity returns the cpu affinity mask of the thread tid.
+func ThreadAffinity(tid int) (Bitmask, error) {
+	return nil, syscall.ENOSYS
+}
+
+// SetThreadAffinity sets the cpu affinity mask of the thread tid.
+func SetThreadAffinity(tid int, cpumask Bitmask) error {
+	return syscall.ENOSYS
+}
+
+// Threads returns the placement of every thread of the process pid.
+func Threads(pid int) ([]ThreadInfo, error) {
+	return nil, syscall.ENOSYS
+}
@@ -0,0 +1,90 @@
+package numa
+
+import (
+	"errors"
+	"runtime"
+	"syscall"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestDo(t *testing.T) {
+	if !Available() {
+		t.Skip("skip by not available")
+	}
+	runtime.LockOSThread()
+	defer runtime.UnlockOSThread()
+
+	assert := require.New(t)
+	prevmask, err := RunningCPUMask()
+	assert.NoError(err)
+	prevpolicy, err := CurrentPolicy()
+	assert.NoError(err)
+
+	NodeMask().ForEach(func(node int) bool {
+		ran := false
+		assert.NoError(Do(node, func() {
+			ran = true
+			cpumask, err := NodeToCPUMask(node)
+			assert.NoError(err)
+			gotmask, err := RunningCPUMask()
+			assert.NoError(err)
+			assert.True(cpumask.Equal(gotmask))
+			p, err := CurrentPolicy()
+			assert.NoError(err)
+			assert.Equal(MPOL_PREFERRED, p.Mode)
+			assert.Equal([]int{node}, nodesof(p.Nodes))
+			cpu, n := GetCPUAndNode()
+			assert.True(cpumask.Get(cpu), "cpu %d", cpu)
+			assert.Equal(node, n)
+		}))
+		assert.True(ran)
+		return true
+	})
+
+	gotmask, err := RunningCPUMask()
+	assert.NoError(err)
+	assert.True(prevmask.Equal(gotmask))
+	gotpolicy, err := CurrentPolicy()
+	assert.NoError(err)
+	assert.Equal(prevpolicy, gotpolicy)
+
+	assert.Error(Do(-1, func() { t.Fatal("unreachable") }))
+	assert.Error(Do(MaxPossibleNodeID()+1, func() { t.Fatal("unreachable") }))
+}
+
+func TestBindThreadApplyError(t *testing.T) {
+	if !Available() {
+		t.Skip("skip by not available")
+	}
+	runtime.LockOSThread()
+	defer runtime.UnlockOSThread()
+
+	assert := require.New(t)
+	prevmask, err := RunningCPUMask()
+	assert.NoError(err)
+	cpumask, err := NodeToCPUMask(0)
+	assert.NoError(err)
+
+	_, err = bindthread(cpumask, Policy{Mode: MPOL_MAX})
+	assert.Error(err)
+	_, ok := err.(*restoreerror)
+	assert.False(ok, "%v", err)
+	assert.False(ispinned(Gettid()))
+	gotmask, err := RunningCPUMask()
+	assert.NoError(err)
+	assert.True(prevmask.Equal(gotmask))
+
+	err = &restoreerror{err: syscall.EPERM}
+	assert.True(errors.Is(err, syscall.EPERM))
+	assert.Contains(err.Error(), syscall.EPERM.Error())
+}
+
+func nodesof(mask Bitmask) (nodes []int) {
+	mask.ForEach(func(i int) bool {
+		nodes = append(nodes, i)
+		return true
+	})
+	return
+}
@@ -0,0 +1,421 @@
+package numa
+
+import (
+	"fmt"
+	"io/fs"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"syscall"
+)
+
+// Topology is an immutable snapshot of the NUMA topology of a platform, which
+// includes the nodes, the cpus and the mapping between them. The methods of
+// Topology mirror the package level functions, so several snapshots can be
+// held side by side, e.g. before and after a cpu hotplug, and be passed
+// explicitly. All methods are safe for concurrent use.
+type Topology struct {
+	fsys fs.FS
+	// The max possible node count, which represents the node count of local
+	// platform supporting.
+	// nnodemax =@nodemask_sz+1
+	nnodemax int
+	// The max configured(enabled/setuped) node, which represents the
+	// available node count of local platform.
+	// nconfigurednode =@maxconfigurednode+1
+	nconfigurednode int
+	// The max possible cpu count, which represents the cpu count of local
+	// platform supporting.
+	// ncpumax =@cpumask_sz+1
+	ncpumax int
+	// nconfiguredcpu =@maxconfiguredcpu
+	nconfiguredcpu int
+
+	memnodes  Bitmask
+	numanodes Bitmask
+	// hasmemory is the nodes which MemTotal is not zero, the memory-less
+	// nodes are excluded.
+	hasmemory Bitmask
+
+	cpu2node map[int]int
+	node2cpu map[int]Bitmask
+
+	// distances[i][j] is the distance from node i to node j, zero if unknown.
+	distances [][]int
+}
+
+// LoadTopology loads the NUMA topology from the given file system, which
+// represents the root directory of a linux platform, e.g. os.DirFS("/"). The
+// topology is discovered from the sys/devices/system and proc/self entries
+// of fsys, so a fake platform can be described by a directory tree.
+func LoadTopology(fsys fs.FS) (*Topology, error) {
+	t, err := loadTopology(fsys, false)
+	if err != nil {
+		return nil, err
+	}
+	return t, nil
+}
+
+// loadTopology loads the topology from fsys. If probe is true, the syscalls
+// are used to detect the max possible node and cpu count of current platform.
+func loadTopology(fsys fs.FS, probe bool) (*Topology, error) {
+	t := &Topology{fsys: fsys}
+	nodes, err := t.readnodes()
+	t.nnodemax = t.setupnodemask(nodes, probe)
+	t.memnodes = NewBitmask(t.nnodemax)
+	t.numanodes = NewBitmask(t.nnodemax)
+	t.hasmemory = NewBitmask(t.nnodemax)
+	t.nconfigurednode = t.setupconfigurednodes(nodes)
+	t.ncpumax = t.setupncpu(probe)
+	t.nconfiguredcpu = t.setupnconfiguredcpu()
+	t.setupconstraints()
+	t.setupdistances()
+	return t, err
+}
+
+func (t *Topology) readnodes() ([]int, error) {
+	files, err := t.readdir("sys/devices/system/node")
+	if err != nil {
+		return nil, err
+	}
+	var nodes []int
+	for _, f := range files {
+		if !strings.HasPrefix(f.Name(), "node") {
+			continue
+		}
+		if i, err := strconv.Atoi(f.Name()[4:]); err == nil {
+			nodes = append(nodes, i)
+		}
+	}
+	sort.Ints(nodes)
+	return nodes, nil
+}
+
+/*
+ * (do this the way Paul Jackson's libcpuset does it)
+ * The nodemask values in /proc/self/status are in an
+ * ascii format that uses 9 characters for each 32 bits of mask.
+ * (this could also be used to find the cpumask size)
+ */
+func (t *Topology) setupnodemask(nodes []int, probe bool) (n int) {
+	d, err := t.readfile("proc/self/status")
+	if err == nil {
+		const stp = "Mems_allowed:\t"
+		for _, line := range strings.Split(string(d), "\n") {
+			if !strings.HasPrefix(line, stp) {
+				continue
+			}
+			n = (len(line) - len(stp) + 1) * 32 / 9
+		}
+	}
+	if n == 0 && probe {
+		n = probenodemask()
+	}
+	if n == 0 {
+		n = roundup64(t.readmaxid("sys/devices/system/node/possible") + 1)
+	}
+	if len(nodes) != 0 && n <= nodes[len(nodes)-1] {
+		n = roundup64(nodes[len(nodes)-1] + 1)
+	}
+	return
+}
+
+func (t *Topology) setupconfigurednodes(nodes []int) (n int) {
+	if len(nodes) == 0 {
+		return 1
+	}
+	for _, i := range nodes {
+		if n < i {
+			n = i // maybe some node absence
+		}
+		t.numanodes.Set(i, true)
+		if total, _, err := t.NodeMemSize64(i); err == nil {
+			t.memnodes.Set(i, true)
+			if total > 0 {
+				t.hasmemory.Set(i, true)
+			}
+		}
+	}
+	n++
+	return
+}
+
+func (t *Topology) setupncpu(probe bool) (n int) {
+	if probe {
+		if n = probencpu(); n != 0 {
+			return
+		}
+	}
+	if n = t.readmaxid("sys/devices/system/cpu/possible") + 1; n == 0 {
+		return 128
+	}
+	return roundup64(n)
+}
+
+func (t *Topology) setupnconfiguredcpu() (n int) {
+	// sysconf(_SC_NPROCESSORS_CONF)
+	files, err := t.readdir("sys/devices/system/cpu")
+	if err == nil {
+		for _, f := range files {
+			if !f.IsDir() || !strings.HasPrefix(f.Name(), "cpu") {
+				continue
+			}
+			if _, err := strconv.Atoi(f.Name()[3:]); err == nil {
+				n++
+			}
+		}
+		return
+	}
+	// fail back
+	d, _ := t.readfile("proc/cpuinfo")
+	for _, line := range strings.Split(string(d), "\n") {
+		if strings.HasPrefix(line, "processor") {
+			n++
+		}
+	}
+	if n == 0 {
+		n = 1
+	}
+	return
+}
+
+func (t *Topology) setupconstraints() {
+	t.node2cpu = make(map[int]Bitmask)
+	t.cpu2node = make(map[int]int)
+	for i := t.numanodes.First(); i >= 0; i = t.numanodes.NextSet(i + 1) {
+		fname := fmt.Sprintf("sys/devices/system/node/node%d/cpumap", i)
+		d, err := t.readfile(fname)
+		if err != nil {
+			continue
+		}
+		mask, err := ParseHexMask(string(d))
+		if err != nil {
+			continue
+		}
+		cpumask := NewBitmask(t.nconfiguredcpu)
+		copy(cpumask, mask)
+		t.node2cpu[i] = cpumask
+		cpumask.ForEach(func(j int) bool {
+			t.cpu2node[j] = i
+			return true
+		})
+	}
+}
+
+func (t *Topology) setupdistances() {
+	t.distances = make([][]int, t.nconfigurednode)
+	for i := range t.distances {
+		t.distances[i] = make([]int, t.nconfigurednode)
+	}
+	// The distance file lists the distances to all online nodes in the order
+	// of their node id.
+	var online []int
+	t.numanodes.ForEach(func(i int) bool {
+		online = append(online, i)
+		return true
+	})
+	for _, i := range online {
+		fname := fmt.Sprintf("sys/devices/system/node/node%d/distance", i)
+		d, err := t.readfile(fname)
+		if err != nil {
+			continue
+		}
+		for j, token := range strings.Fields(string(d)) {
+			if j >= len(online) {
+				break
+			}
+			v, err := strconv.Atoi(token)
+			if err != nil {
+				break
+			}
+			t.distances[i][online[j]] = v
+		}
+	}
+}
+
+// readmaxid returns the max id of the cpulist/nodelist file, such as
+// "0-3,8-11", it returns -1 if the file is absent or malformed.
+func (t *Topology) readmaxid(name string) int {
+	d, err := t.readfile(name)
+	if err != nil {
+		return -1
+	}
+	mask, err := ParseList(string(d))
+	if err != nil {
+		return -1
+	}
+	return mask.Last()
+}
+
+// readfile reads the named file of t.fsys. The topology of non-linux platform
+// has no file system, it returns ENOSYS.
+func (t *Topology) readfile(name string) ([]byte, error) {
+	if t.fsys == nil {
+		return nil, syscall.ENOSYS
+	}
+	return fs.ReadFile(t.fsys, name)
+}
+
+// readdir reads the named directory of t.fsys like readfile.
+func (t *Topology) readdir(name string) ([]fs.DirEntry, error) {
+	if t.fsys == nil {
+		return nil, syscall.ENOSYS
+	}
+	return fs.ReadDir(t.fsys, name)
+}
+
+func roundup64(n int) int { return (n + 63) / 64 * 64 }
+
+// Equal reports whether t and o describe the same nodes, cpus, mapping and
+// distances.
+func (t *Topology) Equal(o *Topology) bool {
+	if t == nil || o == nil {
+		return t == o
+	}
+	return t.nnodemax == o.nnodemax &&
+		t.nconfigurednode == o.nconfigurednode &&
+		t.ncpumax == o.ncpumax &&
+		t.nconfiguredcpu == o.nconfiguredcpu &&
+		reflect.DeepEqual(t.memnodes, o.memnodes) &&
+		reflect.DeepEqual(t.numanodes, o.numanodes) &&
+		reflect.DeepEqual(t.hasmemory, o.hasmemory) &&
+		reflect.DeepEqual(t.cpu2node, o.cpu2node) &&
+		reflect.DeepEqual(t.node2cpu, o.node2cpu) &&
+		reflect.DeepEqual(t.distances, o.distances)
+}
+
+// MaxNodeID returns the max id of configured NUMA nodes.
+func (t *Topology) MaxNodeID() int {
+	return t.nconfigurednode - 1
+}
+
+// MaxPossibleNodeID returns the max possible node id of this platform supported.
+func (t *Topology) MaxPossibleNodeID() int {
+	return t.nnodemax - 1
+}
+
+// NodeCount returns the count of configured NUMA nodes.
+func (t *Topology) NodeCount() int {
+	return t.memnodes.OnesCount()
+}
+
+// NodeMask returns the mask of configured nodes.
+func (t *Topology) NodeMask() Bitmask {
+	return t.memnodes.Clone()
+}
+
+// NodePossibleCount returns the possible NUMA nodes count of this platform
+// supported.
+func (t *Topology) NodePossibleCount() int {
+	return t.nnodemax
+}
+
+// CPUPossibleCount returns the possible cpu count of this platform supported.
+func (t *Topology) CPUPossibleCount() int {
+	return t.ncpumax
+}
+
+// CPUCount returns the configured(enabled/detected) cpu count.
+func (t *Topology) CPUCount() int {
+	return t.nconfiguredcpu
+}
+
+// NodeToCPUMask returns the cpumask of given node id.
+func (t *Topology) NodeToCPUMask(node int) (Bitmask, error) {
+	if node > t.MaxPossibleNodeID() {
+		return nil, fmt.Errorf("node %d is out of range", node)
+	}
+	cpumask, ok := t.node2cpu[node]
+	if !ok {
+		return nil, fmt.Errorf("node %d not found", node)
+	}
+	return cpumask.Clone(), nil
+}
+
+// nodescpumask returns the union of the cpumasks of given nodes, the nodes
+// which are not configured are ignored.
+func (t *Topology) nodescpumask(mask Bitmask) (Bitmask, error) {
+	cpumask := NewBitmask(t.CPUPossibleCount())
+	m := mask.And(t.memnodes)
+	for i := m.First(); i >= 0; i = m.NextSet(i + 1) {
+		cpu, err := t.NodeToCPUMask(i)
+		if err != nil {
+			return nil, err
+		}
+		cpu.ForEach(func(j int) bool {
+			cpumask.Set(j, true)
+			return true
+		})
+	}
+	return cpumask, nil
+}
+
+// CPUToNode returns the node id by given cpu id.
+func (t *Topology) CPUToNode(cpu int) (int, error) {
+	node, ok := t.cpu2node[cpu]
+	if !ok {
+		return 0, fmt.Errorf("cpu %d not found", cpu)
+	}
+	return node, nil
+}
+
+// Distance returns the distance between node from and node to.
+func (t *Topology) Distance(from, to int) (int, error) {
+	if from < 0 || from >= len(t.distances) || to < 0 || to >= len(t.distances) {
+		return 0, fmt.Errorf("node %d or %d is out of range", from, to)
+	}
+	d := t.distances[from][to]
+	if d == 0 {
+		return 0, fmt.Errorf("distance from node %d to node %d not found", from, to)
+	}
+	return d, nil
+}
+
+// DistanceMatrix returns a copy of the distances between all configured
+// nodes, which indexed by node id.
+func (t *Topology) DistanceMatrix() [][]int {
+	m := make([][]int, len(t.distances))
+	for i, row := range t.distances {
+		m[i] = append([]int(nil), row...)
+	}
+	return m
+}
+
+// NodesByDistance returns the nodes which have memory ordered by the distance
+// from the given node, nearest first, ties broken by node id.
+func (t *Topology) NodesByDistance(node int) []int {
+	return t.nodesbydistance(node, t.hasmemory)
+}
+
+// AllNodesByDistance likes NodesByDistance, but the memory-less nodes are
+// included.
+func (t *Topology) AllNodesByDistance(node int) []int {
+	return t.nodesbydistance(node, t.numanodes)
+}
+
+func (t *Topology) nodesbydistance(node int, mask Bitmask) []int {
+	if node < 0 || node >= len(t.distances) || !t.numanodes.Get(node) {
+		return nil
+	}
+	var nodes []int
+	mask.ForEach(func(i int) bool {
+		if i < len(t.distances) && t.distances[node][i] != 0 {
+			nodes = append(nodes, i)
+		}
+		return true
+	})
+	sort.SliceStable(nodes, func(i, j int) bool {
+		return t.distances[node][nodes[i]] < t.distances[node][nodes[j]]
+	})
+	return nodes
+}
+
+// NodeMemSize64 return the memory total size and free size of given node.
+func (t *Topology) NodeMemSize64(node int) (total int64, free int64, err error) {
+	info, err := t.NodeMemInfo(node)
+	if err != nil {
+		return
+	}
+	return int64(info.MemTotal), int64(info.MemFree), nil
+}
@@ -0,0 +1,243 @@
+package numa
+
+import (
+	"os"
+	"path/filepath"
+	"sync"
+	"sync/atomic"
+	"syscall"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func loadTestTopology(t *testing.T, name string) *Topology {
+	topo, err := LoadTopology(os.DirFS(filepath.Join("testdata", name)))
+	require.NoError(t, err, name)
+	return topo
+}
+
+// memorynodes returns the nodes of current platform which have memory, which
+// can be bound by MPOL_BIND.
+func memorynodes() Bitmask {
+	return SystemTopology().hasmemory.Clone()
+}
+
+func TestLoadTopology(t *testing.T) {
+	var tt = []struct {
+		name         string
+		maxnode      int
+		nodes        []int
+		cpus         int
+		cpupossible  int
+		nodepossible int
+		cpu2node     map[int]int
+		distance     [][3]int
+		nearest      map[int][]int
+	}{
+		{
+			name: "single-node", maxnode: 0, nodes: []int{0},
+			cpus: 8, cpupossible: 64, nodepossible: 1024,
+			cpu2node: map[int]int{0: 0, 7: 0},
+			distance: [][3]int{{0, 0, 10}},
+			nearest:  map[int][]int{0: {0}},
+		},
+		{
+			name: "dual-socket", maxnode: 1, nodes: []int{0, 1},
+			cpus: 48, cpupossible: 64, nodepossible: 1024,
+			cpu2node: map[int]int{0: 0, 11: 0, 12: 1, 23: 1, 24: 0, 35: 0, 36: 1, 47: 1},
+			distance: [][3]int{{0, 0, 10}, {0, 1, 21}, {1, 0, 21}, {1, 1, 10}},
+			nearest:  map[int][]int{0: {0, 1}, 1: {1, 0}},
+		},
+		{
+			name: "quad-socket", maxnode: 3, nodes: []int{0, 1, 2, 3},
+			cpus: 64, cpupossible: 64, nodepossible: 1024,
+			cpu2node: map[int]int{0: 0, 15: 0, 16: 1, 32: 2, 63: 3},
+			distance: [][3]int{{0, 3, 22}, {1, 2, 22}, {2, 0, 16}, {3, 3, 10}},
+			nearest:  map[int][]int{0: {0, 1, 2, 3}, 1: {1, 0, 3, 2}, 3: {3, 1, 2, 0}},
+		},
+		{
+			name: "firecracker-vm", maxnode: 0, nodes: []int{0},
+			cpus: 1, cpupossible: 64, nodepossible: 1024,
+			cpu2node: map[int]int{0: 0},
+			distance: [][3]int{{0, 0, 10}},
+			nearest:  map[int][]int{0: {0}},
+		},
+		{
+			name: "sparse-memoryless", maxnode: 2, nodes: []int{0, 2},
+			cpus: 16, cpupossible: 64, nodepossible: 1024,
+			cpu2node: map[int]int{0: 0, 7: 0, 8: 2, 15: 2},
+			distance: [][3]int{{0, 2, 20}, {2, 0, 20}, {2, 2, 10}},
+			nearest:  map[int][]int{0: {0}, 1: nil, 2: {0}},
+		},
+	}
+
+	for _, v := range tt {
+		var (
+			assert = require.New(t)
+			topo   = loadTestTopology(t, v.name)
+		)
+		assert.Equal(v.maxnode, topo.MaxNodeID(), v.name)
+		assert.Equal(len(v.nodes), topo.NodeCount(), v.name)
+		mask := topo.NodeMask()
+		for _, n := range v.nodes {
+			assert.True(mask.Get(n), "%s node %d", v.name, n)
+		}
+		assert.Equal(v.cpus, topo.CPUCount(), v.name)
+		assert.Equal(v.cpupossible, topo.CPUPossibleCount(), v.name)
+		assert.Equal(v.nodepossible, topo.NodePossibleCount(), v.name)
+		for cpu, node := range v.cpu2node {
+			n, err := topo.CPUToNode(cpu)
+			assert.NoError(err, "%s cpu %d", v.name, cpu)
+			assert.Equal(node, n, "%s cpu %d", v.name, cpu)
+			cpumask, err := topo.NodeToCPUMask(node)
+			assert.NoError(err)
+			assert.True(cpumask.Get(cpu), "%s cpu %d", v.name, cpu)
+		}
+		_, err := topo.CPUToNode(v.cpus)
+		assert.Error(err, v.name)
+		for _, d := range v.distance {
+			n, err := topo.Distance(d[0], d[1])
+			assert.NoError(err, "%s %v", v.name, d)
+			assert.Equal(d[2], n, "%s %v", v.name, d)
+		}
+		for node, nodes := range v.nearest {
+			assert.Equal(nodes, topo.NodesByDistance(node), "%s node %d", v.name, node)
+		}
+		total, free, err := topo.NodeMemSize64(v.nodes[0])
+		assert.NoError(err, v.name)
+		assert.True(total > 0 && free > 0 && free < total, v.name)
+	}
+
+	_, err := LoadTopology(os.DirFS(filepath.Join("testdata", "absent")))
+	require.Error(t, err)
+}
+
+func TestTopologyWithoutFS(t *testing.T) {
+	assert := require.New(t)
+	topo, err := LoadTopology(nil)
+	assert.Equal(syscall.ENOSYS, err)
+	assert.Nil(topo)
+
+	// The topology of non-linux platform has no file system.
+	topo = &Topology{}
+	_, err = topo.NodeMemInfo(0)
+	assert.Equal(syscall.ENOSYS, err)
+	_, _, err = topo.NodeMemSize64(0)
+	assert.Equal(syscall.ENOSYS, err)
+	_, err = topo.NodeStat(0)
+	assert.Equal(syscall.ENOSYS, err)
+	_, err = topo.NodeVMStat(0)
+	assert.Equal(syscall.ENOSYS, err)
+}
+
+func TestMemorylessNodes(t *testing.T) {
+	var (
+		assert = require.New(t)
+		topo   = loadTestTopology(t, "sparse-memoryless")
+	)
+	// The memory-less node is configured, like the baseline semantics of
+	// NodeCount and NodeMask.
+	assert.Equal(2, topo.NodeCount())
+	assert.True(topo.NodeMask().Get(2))
+	cpumask, err := topo.NodeToCPUMask(2)
+	assert.NoError(err)
+	assert.Equal(8, cpumask.OnesCount())
+
+	assert.Equal([]int{0}, topo.NodesByDistance(2))
+	assert.Equal([]int{2, 0}, topo.AllNodesByDistance(2))
+	assert.Equal([]int{0, 2}, topo.AllNodesByDistance(0))
+	assert.Nil(topo.AllNodesByDistance(1))
+
+	dual := loadTestTopology(t, "dual-socket")
+	assert.Equal(dual.NodesByDistance(1), dual.AllNodesByDistance(1))
+}
+
+func TestNodesCPUMask(t *testing.T) {
+	var (
+		assert = require.New(t)
+		topo   = loadTestTopology(t, "dual-socket")
+	)
+	node0, err := topo.NodeToCPUMask(0)
+	assert.NoError(err)
+	node1, err := topo.NodeToCPUMask(1)
+	assert.NoError(err)
+
+	mask := NewBitmask(topo.NodePossibleCount())
+	mask.Set(0, true)
+	cpumask, err := topo.nodescpumask(mask)
+	assert.NoError(err)
+	// Only the cpus of node 0 rather than all cpus.
+	assert.Equal(24, cpumask.OnesCount())
+	assert.True(cpumask.Equal(node0))
+	assert.False(cpumask.Intersects(node1))
+
+	mask.Set(1, true)
+	cpumask, err = topo.nodescpumask(mask)
+	assert.NoError(err)
+	assert.Equal(48, cpumask.OnesCount())
+
+	// The memory-less node is configured, but the absent node is ignored.
+	topo = loadTestTopology(t, "sparse-memoryless")
+	mask = NewBitmask(topo.NodePossibleCount())
+	mask.Set(1, true)
+	cpumask, err = topo.nodescpumask(mask)
+	assert.NoError(err)
+	assert.True(cpumask.IsEmpty())
+	mask.Set(2, true)
+	cpumask, err = topo.nodescpumask(mask)
+	assert.NoError(err)
+	assert.Equal(8, cpumask.OnesCount())
+}
+
+func TestTopologyEqual(t *testing.T) {
+	var (
+		assert = require.New(t)
+		dual   = loadTestTopology(t, "dual-socket")
+		quad   = loadTestTopology(t, "quad-socket")
+	)
+	assert.True(dual.Equal(loadTestTopology(t, "dual-socket")))
+	assert.False(dual.Equal(quad))
+	assert.False(dual.Equal(nil))
+
+	sys, err := LoadSystemTopology()
+	assert.NoError(err)
+	assert.True(SystemTopology().Equal(sys))
+	assert.Equal(NodeMask(), sys.NodeMask())
+	assert.Equal(CPUCount(), sys.CPUCount())
+}
+
+func TestRefresh(t *testing.T) {
+	var (
+		assert = require.New(t)
+		prev   = SystemTopology()
+		wg     sync.WaitGroup
+		done   = make(chan struct{})
+		bad    int64
+	)
+	// The readers count the bad snapshots, which are asserted on the test
+	// goroutine.
+	for i := 0; i < 4; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for {
+				select {
+				case <-done:
+					return
+				default:
+				}
+				if CPUCount() <= 0 || NodeCount() <= 0 {
+					atomic.AddInt64(&bad, 1)
+				}
+			}
+		}()
+	}
+	for i := 0; i < 10; i++ {
+		assert.NoError(Refresh())
+	}
+	close(done)
+	wg.Wait()
+	assert.Zero(atomic.LoadInt64(&bad))
+	assert.True(prev.Equal(SystemTopology()))
+}
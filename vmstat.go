@@ -0,0 +1,38 @@
+package numa
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// VMStat is the virtual memory counters of a node, which parsed from
+// /sys/devices/system/node/nodeN/vmstat and keyed by the counter name, such
+// as nr_free_pages, nr_anon_pages, pgpromote_success, pgdemote_kswapd. The
+// counters vary between kernel versions, the absent ones are not in the map.
+type VMStat map[string]uint64
+
+// NodeVMStat returns the virtual memory counters of given node.
+func (t *Topology) NodeVMStat(node int) (VMStat, error) {
+	fname := fmt.Sprintf("sys/devices/system/node/node%d/vmstat", node)
+	d, err := t.readfile(fname)
+	if err != nil {
+		return nil, err
+	}
+	stat := make(VMStat)
+	for _, line := range strings.Split(string(d), "\n") {
+		tokens := strings.Fields(line)
+		if len(tokens) == 0 {
+			continue
+		}
+		if len(tokens) != 2 {
+			return nil, fmt.Errorf("invalid line %q in %s", line, fname)
+		}
+		v, err := strconv.ParseUint(tokens[1], 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid line %q in %s: %v", line, fname, err)
+		}
+		stat[tokens[0]] = v
+	}
+	return stat, nil
+}
@@ -0,0 +1,44 @@
+package numa
+
+import (
+	"runtime"
+	"syscall"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestTopologyNodeVMStat(t *testing.T) {
+	var (
+		assert = require.New(t)
+		topo   = loadTestTopology(t, "quad-socket")
+	)
+	stat, err := topo.NodeVMStat(1)
+	assert.NoError(err)
+	assert.Len(stat, 68)
+	assert.Equal(uint64(12582912), stat["nr_free_pages"])
+	assert.Equal(uint64(373975), stat["nr_anon_pages"])
+	assert.Equal(uint64(564031), stat["pgpromote_success"])
+	assert.Equal(uint64(587788), stat["pgdemote_kswapd"])
+	_, ok := stat["numa_pte_updates"]
+	assert.False(ok)
+
+	_, err = topo.NodeVMStat(4)
+	assert.Error(err)
+}
+
+func TestNodeVMStat(t *testing.T) {
+	assert := require.New(t)
+	NodeMask().ForEach(func(node int) bool {
+		stat, err := NodeVMStat(node)
+		// The vmstat is read from sysfs on linux, which does not depend on
+		// the NUMA syscalls.
+		if runtime.GOOS != "linux" {
+			assert.Equal(syscall.ENOSYS, err, "node %d", node)
+			return true
+		}
+		assert.NoError(err, "node %d", node)
+		assert.Contains(stat, "nr_free_pages", "node %d", node)
+		return true
+	})
+}